@@ -0,0 +1,313 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultAsyncBufferSize     = 1024
+	defaultAsyncReportInterval = 10 * time.Second
+	// dropReportInterval is how often the network writers report the entries dropped on the full buffer.
+	dropReportInterval = 10 * time.Second
+)
+
+// AsyncPolicy determines what AsyncWriter does when its buffer is full.
+type AsyncPolicy int8
+
+const (
+	// AsyncBlock blocks the caller until the buffer has free space.
+	AsyncBlock AsyncPolicy = iota
+	// AsyncDropNewest drops the entry being written.
+	AsyncDropNewest
+	// AsyncDropOldest drops the oldest buffered entry to make room for the new one.
+	AsyncDropOldest
+)
+
+// AsyncWriter is a zerolog.LevelWriter that buffers entries in memory and writes them
+// to the inner writer from a single background goroutine, so logging doesn't block on slow outputs.
+//
+// Fatal and panic entries are written synchronously after flushing the buffer.
+type AsyncWriter struct {
+	out            io.Writer
+	policy         AsyncPolicy
+	reportInterval time.Duration
+	reporter       func(dropped uint64)
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	buf        []asyncEntry
+	head       int
+	count      int
+	writing    bool
+	reportDue  bool
+	closed     bool
+	unreported uint64
+
+	dropped atomic.Uint64
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type asyncEntry struct {
+	level zerolog.Level
+	p     []byte
+}
+
+type AsyncOption func(*AsyncWriter)
+
+// WithAsyncBufferSize sets the number of entries the buffer can hold (1024 by default).
+func WithAsyncBufferSize(size int) AsyncOption {
+	return func(w *AsyncWriter) {
+		if size > 0 {
+			w.buf = make([]asyncEntry, size)
+		}
+	}
+}
+
+// WithAsyncPolicy sets the behaviour on the full buffer (AsyncBlock by default).
+func WithAsyncPolicy(policy AsyncPolicy) AsyncOption {
+	return func(w *AsyncWriter) {
+		w.policy = policy
+	}
+}
+
+// WithAsyncReportInterval sets how often the number of dropped entries is reported (10 seconds by default).
+func WithAsyncReportInterval(interval time.Duration) AsyncOption {
+	return func(w *AsyncWriter) {
+		w.reportInterval = interval
+	}
+}
+
+// WithAsyncReporter replaces the default report of dropped entries.
+//
+// By default, a JSON entry with WARN level is written to the inner writer.
+// The function is called from the background goroutine, so it may write to the inner writer directly.
+func WithAsyncReporter(f func(dropped uint64)) AsyncOption {
+	return func(w *AsyncWriter) {
+		w.reporter = f
+	}
+}
+
+// NewAsyncWriter creates an asynchronous writer over w and starts its background goroutine.
+//
+// Close must be called (usually deferred after Init) to write out the buffered entries.
+func NewAsyncWriter(w io.Writer, opts ...AsyncOption) *AsyncWriter {
+	aw := &AsyncWriter{
+		out:            w,
+		buf:            make([]asyncEntry, defaultAsyncBufferSize),
+		reportInterval: defaultAsyncReportInterval,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	aw.cond = sync.NewCond(&aw.mu)
+	aw.reporter = aw.report
+	for _, opt := range opts {
+		opt(aw)
+	}
+
+	go aw.run()
+	if aw.reportInterval > 0 {
+		go aw.tick()
+	}
+
+	return aw
+}
+
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *AsyncWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed || level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		return w.writeSync(level, p)
+	}
+
+	for w.count == len(w.buf) {
+		switch w.policy {
+		case AsyncDropNewest:
+			w.drop()
+			return len(p), nil
+		case AsyncDropOldest:
+			w.head = (w.head + 1) % len(w.buf)
+			w.count--
+			w.drop()
+		default:
+			w.cond.Wait()
+			if w.closed {
+				return w.writeSync(level, p)
+			}
+		}
+	}
+
+	// The caller may reuse p after the return, so the entry is copied.
+	w.buf[(w.head+w.count)%len(w.buf)] = asyncEntry{level: level, p: append([]byte(nil), p...)}
+	w.count++
+	w.cond.Broadcast()
+	return len(p), nil
+}
+
+// Dropped returns the total number of the dropped entries.
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Flush blocks until all the buffered entries are written to the inner writer.
+func (w *AsyncWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.count > 0 || w.writing {
+		w.cond.Wait()
+	}
+}
+
+// Close writes out the buffered entries and stops the background goroutine.
+// Entries written after Close are written to the inner writer synchronously.
+//
+// The inner writer is not closed.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.stop)
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// writeSync waits for the buffer to be written out and writes the entry directly.
+// Must be called with the lock held.
+func (w *AsyncWriter) writeSync(level zerolog.Level, p []byte) (int, error) {
+	for w.count > 0 || w.writing {
+		w.cond.Wait()
+	}
+	return writeLevel(w.out, level, p)
+}
+
+// drop must be called with the lock held.
+func (w *AsyncWriter) drop() {
+	w.unreported++
+	w.dropped.Add(1)
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	batch := make([]asyncEntry, 0, len(w.buf))
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed && !w.reportDue {
+			w.cond.Wait()
+		}
+
+		batch = batch[:0]
+		for ; w.count > 0; w.count-- {
+			batch = append(batch, w.buf[w.head])
+			w.buf[w.head] = asyncEntry{}
+			w.head = (w.head + 1) % len(w.buf)
+		}
+		dropped := w.unreported
+		if w.reportDue || w.closed {
+			w.unreported = 0
+			w.reportDue = false
+		} else {
+			dropped = 0
+		}
+		closed := w.closed
+		w.writing = true
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		for _, e := range batch {
+			_, _ = writeLevel(w.out, e.level, e.p)
+		}
+		if dropped > 0 {
+			w.reporter(dropped)
+		}
+
+		w.mu.Lock()
+		w.writing = false
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		if closed {
+			return
+		}
+	}
+}
+
+func (w *AsyncWriter) tick() {
+	ticker := time.NewTicker(w.reportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.unreported > 0 {
+				w.reportDue = true
+				w.cond.Broadcast()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *AsyncWriter) report(dropped uint64) {
+	zl := zerolog.New(w.out).With().Timestamp().Logger()
+	zl.Warn().Uint64("dropped", dropped).Msg(fmt.Sprintf("%d log entries dropped", dropped))
+}
+
+func writeLevel(w io.Writer, level zerolog.Level, p []byte) (int, error) {
+	if lw, ok := w.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+	return w.Write(p)
+}
+
+// droppedCounter counts the entries dropped by the network writers. The entries dropped on the full buffer
+// are reported periodically from the background goroutine, as AsyncWriter does, instead of on every write.
+type droppedCounter struct {
+	total      atomic.Uint64
+	unreported atomic.Uint64
+}
+
+// drop counts the entry dropped on the full buffer, it's reported with report.
+func (c *droppedCounter) drop() {
+	c.total.Add(1)
+	c.unreported.Add(1)
+}
+
+// add counts the entries already reported (e.g. with the error of the failed export).
+func (c *droppedCounter) add(n int) {
+	c.total.Add(uint64(n)) //nolint:gosec
+}
+
+func (c *droppedCounter) load() uint64 {
+	return c.total.Load()
+}
+
+// report calls the handler with the number of the entries dropped on the full buffer since the last report.
+func (c *droppedCounter) report(handler func(error), buffer string) {
+	if n := c.unreported.Swap(0); n > 0 {
+		handler(fmt.Errorf("%s is full, %d log entries dropped", buffer, n))
+	}
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks every write until it is released.
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("flush", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		buf := &bytes.Buffer{}
+		w := NewAsyncWriter(buf)
+		defer w.Close()
+		l := NewLogger(InfoLevel, WithOutput(w))
+
+		// Act
+		l.Info(context.Background(), "first")
+		l.Info(context.Background(), "second")
+		w.Flush()
+
+		// Assert
+		out := buf.String()
+		assert.Less(t, strings.Index(out, "first"), strings.Index(out, "second"))
+		assert.Zero(t, w.Dropped())
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		out := newBlockingWriter()
+		w := NewAsyncWriter(out, WithAsyncBufferSize(2), WithAsyncPolicy(AsyncDropNewest))
+		_, _ = w.Write([]byte("0\n"))
+		<-out.started
+
+		// Act
+		for _, p := range []string{"1\n", "2\n", "3\n", "4\n"} {
+			_, _ = w.Write([]byte(p))
+		}
+		close(out.release)
+		require.NoError(t, w.Close())
+
+		// Assert
+		assert.Equal(t, uint64(2), w.Dropped())
+		assert.True(t, strings.HasPrefix(out.String(), "0\n1\n2\n"))
+		assert.Contains(t, out.String(), `"dropped":2`)
+		assert.Contains(t, out.String(), "2 log entries dropped")
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		out := newBlockingWriter()
+		var reported uint64
+		w := NewAsyncWriter(out,
+			WithAsyncBufferSize(2),
+			WithAsyncPolicy(AsyncDropOldest),
+			WithAsyncReporter(func(dropped uint64) { reported += dropped }),
+		)
+		_, _ = w.Write([]byte("0\n"))
+		<-out.started
+
+		// Act
+		for _, p := range []string{"1\n", "2\n", "3\n", "4\n"} {
+			_, _ = w.Write([]byte(p))
+		}
+		close(out.release)
+		require.NoError(t, w.Close())
+
+		// Assert
+		assert.Equal(t, "0\n3\n4\n", out.String())
+		assert.Equal(t, uint64(2), reported)
+	})
+
+	t.Run("write after close", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		buf := &bytes.Buffer{}
+		w := NewAsyncWriter(buf)
+		require.NoError(t, w.Close())
+
+		// Act
+		_, err := w.Write([]byte("late\n"))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "late\n", buf.String())
+	})
+}
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// DefaultContextLevelHeader is the default name of the header that enables the level override.
+const DefaultContextLevelHeader = "x-debug-log"
+
+type levelKey struct{}
+
+// WithContextLevel overrides the level of the loggers used with the returned context.
+//
+// Both the package functions (Debug, Info, ...) and the loggers returned by FromContext honor the override,
+// so it allows to get DEBUG entries for a single request while the logger itself is at INFO.
+// The override only makes the logging more verbose: it's ignored if the level isn't below the logger level.
+func WithContextLevel(ctx context.Context, level Level) context.Context {
+	return context.WithValue(ctx, levelKey{}, level)
+}
+
+// ContextLevel returns the level set with WithContextLevel.
+func ContextLevel(ctx context.Context) (Level, bool) {
+	level, ok := ctx.Value(levelKey{}).(Level)
+	return level, ok
+}
+
+type contextLevelConfig struct {
+	header       string
+	level        Level
+	secretHeader string
+	secret       []byte
+	allowlist    []netip.Prefix
+	anyClient    bool
+}
+
+type ContextLevelOption func(*contextLevelConfig)
+
+// WithContextLevelHeader sets the name of the HTTP header (or gRPC metadata key)
+// that enables the override (DefaultContextLevelHeader by default).
+//
+// The value is either a level name (e.g. "trace") or a boolean-like "1"/"true",
+// which sets the level specified with WithContextLevelDefault.
+func WithContextLevelHeader(name string) ContextLevelOption {
+	return func(c *contextLevelConfig) {
+		c.header = strings.ToLower(name)
+	}
+}
+
+// WithContextLevelDefault sets the level used when the header value is "1" or "true" (DebugLevel by default).
+func WithContextLevelDefault(level Level) ContextLevelOption {
+	return func(c *contextLevelConfig) {
+		c.level = level
+	}
+}
+
+// WithContextLevelSecret requires the specified header (or gRPC metadata key) to hold the shared secret.
+// An empty secret is ignored, it doesn't allow the override.
+func WithContextLevelSecret(header, secret string) ContextLevelOption {
+	return func(c *contextLevelConfig) {
+		if secret == "" {
+			return
+		}
+		c.secretHeader = strings.ToLower(header)
+		c.secret = []byte(secret)
+	}
+}
+
+// WithContextLevelAllowlist allows the override only for the peers with the addresses in the specified networks.
+func WithContextLevelAllowlist(prefixes ...netip.Prefix) ContextLevelOption {
+	return func(c *contextLevelConfig) {
+		c.allowlist = append(c.allowlist, prefixes...)
+	}
+}
+
+// WithContextLevelAnyClient allows any client to enable the override without WithContextLevelSecret
+// or WithContextLevelAllowlist, e.g. for the services reachable only from the trusted network.
+func WithContextLevelAnyClient() ContextLevelOption {
+	return func(c *contextLevelConfig) {
+		c.anyClient = true
+	}
+}
+
+func newContextLevelConfig(opts []ContextLevelOption) *contextLevelConfig {
+	c := &contextLevelConfig{
+		header: DefaultContextLevelHeader,
+		level:  DebugLevel,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// resolve returns the requested level if the request passes the configured checks.
+func (c *contextLevelConfig) resolve(value, secret, addr string) (Level, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if len(c.secret) == 0 && len(c.allowlist) == 0 && !c.anyClient {
+		return 0, false
+	}
+	if len(c.secret) > 0 && subtle.ConstantTimeCompare([]byte(secret), c.secret) != 1 {
+		return 0, false
+	}
+	if len(c.allowlist) > 0 && !c.allowed(addr) {
+		return 0, false
+	}
+
+	switch strings.ToLower(value) {
+	case "1", "true", "on", "yes":
+		return c.level, true
+	}
+	level, err := ParseLevel(value)
+	if err != nil {
+		return 0, false
+	}
+	return level, true
+}
+
+func (c *contextLevelConfig) allowed(addr string) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+
+	ip = ip.Unmap()
+	for _, p := range c.allowlist {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextLevelHandler enables the level override (see WithContextLevel) for the requests
+// with the header (DefaultContextLevelHeader by default).
+//
+// The override requires WithContextLevelSecret or WithContextLevelAllowlist,
+// or WithContextLevelAnyClient to allow it for any client.
+func ContextLevelHandler(next http.Handler, opts ...ContextLevelOption) http.Handler {
+	c := newContextLevelConfig(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var secret string
+		if c.secretHeader != "" {
+			secret = r.Header.Get(c.secretHeader)
+		}
+
+		if level, ok := c.resolve(r.Header.Get(c.header), secret, r.RemoteAddr); ok {
+			r = r.WithContext(WithContextLevel(r.Context(), level))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ContextLevelUnaryServerInterceptor enables the level override (see WithContextLevel) for the calls
+// with the metadata key (DefaultContextLevelHeader by default).
+//
+// The override requires WithContextLevelSecret or WithContextLevelAllowlist,
+// or WithContextLevelAnyClient to allow it for any client.
+func ContextLevelUnaryServerInterceptor(opts ...ContextLevelOption) grpc.UnaryServerInterceptor {
+	c := newContextLevelConfig(opts)
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(c.grpcContext(ctx), req)
+	}
+}
+
+// ContextLevelStreamServerInterceptor is the stream counterpart of ContextLevelUnaryServerInterceptor.
+func ContextLevelStreamServerInterceptor(opts ...ContextLevelOption) grpc.StreamServerInterceptor {
+	c := newContextLevelConfig(opts)
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if levelCtx := c.grpcContext(ctx); levelCtx != ctx {
+			ss = &contextServerStream{ServerStream: ss, ctx: levelCtx}
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (c *contextLevelConfig) grpcContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	var secret, addr string
+	if c.secretHeader != "" {
+		secret = firstMetadataValue(md, c.secretHeader)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	if level, ok := c.resolve(firstMetadataValue(md, c.header), secret, addr); ok {
+		return WithContextLevel(ctx, level)
+	}
+	return ctx
+}
+
+func firstMetadataValue(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// contextServerStream replaces the context of the wrapped server stream.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
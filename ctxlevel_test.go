@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestWithContextLevel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("package functions", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		ctx = ToContext(ctx, FromContext(ctx).Level(InfoLevel))
+
+		// Act
+		Debug(ctx, "hidden")
+		Debug(WithContextLevel(ctx, DebugLevel), "visible")
+
+		// Assert
+		assert.NotContains(t, buf.String(), "hidden")
+		assert.Contains(t, buf.String(), "visible")
+	})
+
+	t.Run("from context", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, _ := getTestData()
+		ctx = ToContext(ctx, FromContext(ctx).Level(InfoLevel))
+
+		// Act
+		l := FromContext(WithContextLevel(ctx, TraceLevel))
+
+		// Assert
+		assert.Equal(t, TraceLevel, l.GetLevel())
+		assert.Equal(t, InfoLevel, FromContext(ctx).GetLevel())
+	})
+
+	t.Run("less verbose", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		ctx = ToContext(ctx, FromContext(ctx).Level(InfoLevel))
+		ctx = WithContextLevel(ctx, ErrorLevel)
+
+		// Act
+		Info(ctx, "visible")
+		l := FromContext(ctx)
+
+		// Assert
+		assert.Contains(t, buf.String(), "visible")
+		assert.Equal(t, InfoLevel, l.GetLevel())
+	})
+}
+
+func TestContextLevelHandler(t *testing.T) {
+	t.Parallel()
+
+	serve := func(wrap func(http.Handler) http.Handler, header http.Header, remoteAddr string) (Level, bool) {
+		var (
+			level Level
+			ok    bool
+		)
+		next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			level, ok = ContextLevel(r.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header = header
+		req.RemoteAddr = remoteAddr
+		wrap(next).ServeHTTP(httptest.NewRecorder(), req)
+		return level, ok
+	}
+
+	tests := []struct {
+		name      string
+		opts      []ContextLevelOption
+		header    http.Header
+		addr      string
+		wantLevel Level
+		wantOK    bool
+	}{
+		{
+			name: "no header",
+		},
+		{
+			name:   "no secret nor allowlist",
+			header: http.Header{"X-Debug-Log": {"1"}},
+		},
+		{
+			name:      "default level",
+			opts:      []ContextLevelOption{WithContextLevelAnyClient()},
+			header:    http.Header{"X-Debug-Log": {"1"}},
+			wantLevel: DebugLevel,
+			wantOK:    true,
+		},
+		{
+			name:      "level name",
+			opts:      []ContextLevelOption{WithContextLevelAnyClient()},
+			header:    http.Header{"X-Debug-Log": {"trace"}},
+			wantLevel: TraceLevel,
+			wantOK:    true,
+		},
+		{
+			name:   "invalid secret",
+			opts:   []ContextLevelOption{WithContextLevelSecret("x-debug-token", "secret")},
+			header: http.Header{"X-Debug-Log": {"1"}, "X-Debug-Token": {"guess"}},
+		},
+		{
+			name:   "empty secret",
+			opts:   []ContextLevelOption{WithContextLevelSecret("x-debug-token", "")},
+			header: http.Header{"X-Debug-Log": {"1"}},
+		},
+		{
+			name:      "valid secret",
+			opts:      []ContextLevelOption{WithContextLevelSecret("x-debug-token", "secret")},
+			header:    http.Header{"X-Debug-Log": {"1"}, "X-Debug-Token": {"secret"}},
+			wantLevel: DebugLevel,
+			wantOK:    true,
+		},
+		{
+			name:   "not allowed peer",
+			opts:   []ContextLevelOption{WithContextLevelAllowlist(netip.MustParsePrefix("10.0.0.0/8"))},
+			header: http.Header{"X-Debug-Log": {"1"}},
+			addr:   "192.168.0.1:1234",
+		},
+		{
+			name:      "allowed peer",
+			opts:      []ContextLevelOption{WithContextLevelAllowlist(netip.MustParsePrefix("10.0.0.0/8"))},
+			header:    http.Header{"X-Debug-Log": {"1"}},
+			addr:      "10.1.2.3:1234",
+			wantLevel: DebugLevel,
+			wantOK:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Act
+			level, ok := serve(func(next http.Handler) http.Handler {
+				return ContextLevelHandler(next, tt.opts...)
+			}, tt.header, tt.addr)
+
+			// Assert
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantLevel, level)
+		})
+	}
+}
+
+func TestContextLevelUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	interceptor := ContextLevelUnaryServerInterceptor(
+		WithContextLevelHeader("X-Verbose"),
+		WithContextLevelAllowlist(netip.MustParsePrefix("127.0.0.0/8")),
+	)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-verbose", "true"))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4321}})
+
+	// Act
+	var (
+		level Level
+		ok    bool
+	)
+	_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		level, ok = ContextLevel(ctx)
+		return nil, nil
+	})
+
+	// Assert
+	assert.True(t, ok)
+	assert.Equal(t, DebugLevel, level)
+}
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrorChainFieldName is the field name for the chain of the wrapped errors.
+// The stack trace is added with the zerolog.ErrorStackFieldName field ("stack").
+const ErrorChainFieldName = "error_chain"
+
+const (
+	maxErrorChainDepth = 32
+	maxStackDepth      = 64
+)
+
+const packagePrefix = "github.com/cdnnow-pro/go-log."
+
+const stackTraceDisabled = Level(zerolog.Disabled)
+
+// stackTraceLevel is the minimal level of the entries to capture the stack trace at the call site for.
+var stackTraceLevel atomic.Int32
+
+func init() {
+	stackTraceLevel.Store(int32(stackTraceDisabled))
+}
+
+// SetStackTraceEnabled sets the global flag that determines whether to capture the stack trace
+// at the call site for the entries with errors, if the error doesn't provide one.
+func SetStackTraceEnabled(enabled bool) {
+	if enabled {
+		SetStackTraceLevel(TraceLevel)
+	} else {
+		SetStackTraceLevel(stackTraceDisabled)
+	}
+}
+
+// SetStackTraceLevel enables the capturing of the stack trace at the call site (see SetStackTraceEnabled)
+// only for the entries with errors at the specified level and above, e.g. FatalLevel.
+func SetStackTraceLevel(level Level) {
+	stackTraceLevel.Store(int32(level))
+}
+
+// errorChain returns the messages and the types of the wrapped errors. The errors joined with errors.Join
+// (or any error with the "Unwrap() []error" method) are nested as the "errors" array of the chains.
+func (l *Logger) errorChain(err error, depth int) []any {
+	var chain []any
+	for ; err != nil && depth < maxErrorChainDepth; depth++ {
+		node := map[string]any{
+			"message": l.redactor.String(err.Error()),
+			"type":    reflect.TypeOf(err).String(),
+		}
+		chain = append(chain, node)
+
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			var branches []any
+			for _, e := range u.Unwrap() {
+				branches = append(branches, l.errorChain(e, depth+1))
+			}
+			node["errors"] = branches
+			return chain
+		default:
+			return chain
+		}
+	}
+	return chain
+}
+
+// isWrapper reports whether the error wraps any other errors.
+func isWrapper(err error) bool {
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return u.Unwrap() != nil
+	case interface{ Unwrap() []error }:
+		return len(u.Unwrap()) > 0
+	}
+	return false
+}
+
+// errorStack returns the program counters of the stack trace provided by the error or any of the wrapped errors
+// with the StackTrace method (e.g. github.com/pkg/errors), whose result is a slice of program counters.
+func errorStack(err error) []uintptr {
+	var pcs []uintptr
+	walkErrors(err, 0, func(e error) bool {
+		pcs = stackTraceOf(e)
+		return pcs == nil
+	})
+	return pcs
+}
+
+func walkErrors(err error, depth int, f func(error) bool) bool {
+	for ; err != nil && depth < maxErrorChainDepth; depth++ {
+		if !f(err) {
+			return false
+		}
+
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				if !walkErrors(e, depth+1, f) {
+					return false
+				}
+			}
+			return true
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+func stackTraceOf(err error) []uintptr {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	st := m.Call(nil)[0]
+	if st.Kind() != reflect.Slice || st.Type().Elem().Kind() != reflect.Uintptr || st.Len() == 0 {
+		return nil
+	}
+
+	pcs := make([]uintptr, st.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(st.Index(i).Uint())
+	}
+	return pcs
+}
+
+// callerStack captures the stack trace at the call site, skipping the frames of this package.
+func callerStack() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs) //nolint:mnd
+	pcs = pcs[:n]
+
+	frames := runtime.CallersFrames(pcs)
+	skip := 0
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) {
+			break
+		}
+		skip++
+		if !more {
+			break
+		}
+	}
+	return pcs[skip:]
+}
+
+func formatStack(pcs []uintptr) []any {
+	var stack []any
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" || frame.File != "" {
+			stack = append(stack, map[string]any{
+				"func":   frame.Function,
+				"source": fmt.Sprintf("%s:%d", frame.File, frame.Line),
+			})
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// withError adds the error (redacted if the redaction is configured), the chain of the wrapped errors
+// and the stack trace to the entry.
+func (l *Logger) withError(event *zerolog.Event, level Level, err error) *zerolog.Event {
+	if err == nil {
+		return event.Err(err)
+	}
+
+	if l.redactor == nil {
+		event = event.Err(err)
+	} else {
+		event = event.Str(zerolog.ErrorFieldName, l.redactor.String(err.Error()))
+	}
+	if isWrapper(err) {
+		event = event.Interface(ErrorChainFieldName, l.errorChain(err, 0))
+	}
+
+	pcs := errorStack(err)
+	if pcs == nil && level >= Level(stackTraceLevel.Load()) {
+		pcs = callerStack()
+	}
+	if len(pcs) > 0 {
+		event = event.Interface(zerolog.ErrorStackFieldName, formatStack(pcs))
+	}
+	return event
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/cdnnow-pro/go-log/logtest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stackError mimics the errors of github.com/pkg/errors.
+type stackError struct {
+	msg   string
+	stack stackTrace
+}
+
+type frame uintptr
+
+type stackTrace []frame
+
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	st := make(stackTrace, n)
+	for i, pc := range pcs[:n] {
+		st[i] = frame(pc)
+	}
+	return &stackError{msg: msg, stack: st}
+}
+
+func (e *stackError) Error() string {
+	return e.msg
+}
+
+func (e *stackError) StackTrace() stackTrace {
+	return e.stack
+}
+
+func TestLogger_ErrorChain(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel)
+	pathErr := &fs.PathError{Op: "open", Path: "/etc/app.conf", Err: fs.ErrNotExist}
+	err := fmt.Errorf("load config: %w", pathErr)
+
+	// Act
+	l.Error(ctx, err, "failed")
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, err.Error(), entries[0].Error)
+	assert.Equal(t, []any{
+		map[string]any{"message": err.Error(), "type": "*fmt.wrapError"},
+		map[string]any{"message": pathErr.Error(), "type": "*fs.PathError"},
+		map[string]any{"message": fs.ErrNotExist.Error(), "type": "*errors.errorString"},
+	}, entries[0].Fields[ErrorChainFieldName])
+	assert.NotContains(t, entries[0].Fields, zerolog.ErrorStackFieldName)
+}
+
+func TestLogger_ErrorChainJoin(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel)
+	first := errors.New("first")
+	second := fmt.Errorf("second: %w", errors.New("cause"))
+	err := fmt.Errorf("close: %w", errors.Join(first, second))
+
+	// Act
+	l.Error(ctx, err, "failed")
+
+	// Assert
+	chain := rec.Entries()[0].Fields[ErrorChainFieldName].([]any)
+	require.Len(t, chain, 2)
+	joined := chain[1].(map[string]any)
+	assert.Equal(t, "*errors.joinError", joined["type"])
+	assert.Equal(t, []any{
+		[]any{
+			map[string]any{"message": "first", "type": "*errors.errorString"},
+		},
+		[]any{
+			map[string]any{"message": "second: cause", "type": "*fmt.wrapError"},
+			map[string]any{"message": "cause", "type": "*errors.errorString"},
+		},
+	}, joined["errors"])
+}
+
+func TestLogger_ErrorWithoutChain(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel)
+
+	// Act
+	l.Error(ctx, errors.New("boom"), "failed")
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "boom", entries[0].Error)
+	assert.NotContains(t, entries[0].Fields, ErrorChainFieldName)
+}
+
+func TestLogger_ErrorStackFromError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel)
+	err := fmt.Errorf("wrapped: %w", newStackError("boom"))
+
+	// Act
+	l.Error(ctx, err, "failed")
+
+	// Assert
+	stack := stackOf(t, rec.Entries()[0])
+	assert.Contains(t, stack[0]["func"], "TestLogger_ErrorStackFromError")
+	assert.Contains(t, stack[0]["source"], "errors_test.go:")
+}
+
+func TestLogger_ErrorStackCaptured(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel)
+
+	// Act
+	SetStackTraceEnabled(true)
+	l.Error(ctx, errors.New("boom"), "captured")
+	SetStackTraceLevel(FatalLevel)
+	l.Error(ctx, errors.New("boom"), "below threshold")
+	SetStackTraceEnabled(false)
+	l.Error(ctx, errors.New("boom"), "disabled")
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 3)
+
+	stack := stackOf(t, entries[0])
+	assert.Contains(t, stack[0]["func"], "TestLogger_ErrorStackCaptured")
+	for _, frame := range stack {
+		assert.False(t, strings.HasPrefix(frame["func"].(string), "github.com/cdnnow-pro/go-log."), frame["func"])
+	}
+
+	assert.NotContains(t, entries[1].Fields, zerolog.ErrorStackFieldName)
+	assert.NotContains(t, entries[2].Fields, zerolog.ErrorStackFieldName)
+}
+
+func stackOf(t *testing.T, e logtest.Entry) []map[string]any {
+	t.Helper()
+
+	raw, ok := e.Fields[zerolog.ErrorStackFieldName].([]any)
+	require.True(t, ok, e.Fields)
+	require.NotEmpty(t, raw)
+
+	stack := make([]map[string]any, 0, len(raw))
+	for _, f := range raw {
+		stack = append(stack, f.(map[string]any))
+	}
+	return stack
+}
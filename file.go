@@ -0,0 +1,394 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	backupTimeFormat = "2006-01-02T15-04-05.000000000"
+	compressSuffix   = ".gz"
+)
+
+// FileWriter is an io.Writer that writes to a file and rotates it by size and age.
+//
+// Rotated files are renamed to "<name>-<timestamp><ext>" in the same directory
+// and optionally compressed with gzip.
+type FileWriter struct {
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	compress   bool
+	signals    []os.Signal
+
+	mu        sync.Mutex
+	file      *os.File
+	size      int64
+	rotateAt  time.Time
+	closed    bool
+	signalsCh chan os.Signal
+	millCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+type FileOption func(*FileWriter)
+
+// WithFileMaxSize sets the maximum size of the file in bytes before it gets rotated.
+func WithFileMaxSize(size int64) FileOption {
+	return func(w *FileWriter) {
+		w.maxSize = size
+	}
+}
+
+// WithFileRotationInterval sets the maximum age of the file before it gets rotated.
+func WithFileRotationInterval(interval time.Duration) FileOption {
+	return func(w *FileWriter) {
+		w.interval = interval
+	}
+}
+
+// WithFileMaxBackups sets the number of rotated files to keep. All of them are kept by default.
+func WithFileMaxBackups(n int) FileOption {
+	return func(w *FileWriter) {
+		w.maxBackups = n
+	}
+}
+
+// WithFileCompression enables gzip compression of the rotated files.
+func WithFileCompression() FileOption {
+	return func(w *FileWriter) {
+		w.compress = true
+	}
+}
+
+// WithFileReopenSignal sets the signals which make the writer reopen the file (SIGHUP if none specified).
+//
+// Allows to use the writer with an external rotation tool (e.g. logrotate).
+func WithFileReopenSignal(signals ...os.Signal) FileOption {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	return func(w *FileWriter) {
+		w.signals = signals
+	}
+}
+
+// NewFileWriter opens (or creates) the file in append mode and returns a writer for it.
+//
+// The writer must be closed with Close to release the file and the background goroutines.
+func NewFileWriter(path string, opts ...FileOption) (*FileWriter, error) {
+	w := &FileWriter{
+		path:   path,
+		millCh: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("cannot create log directory: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.mill()
+
+	if len(w.signals) > 0 {
+		w.signalsCh = make(chan os.Signal, 1)
+		signal.Notify(w.signalsCh, w.signals...)
+		w.wg.Add(1)
+		go w.watchSignals()
+	}
+
+	return w, nil
+}
+
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if err := w.ensureOpen(); err != nil {
+		return 0, err
+	}
+
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it and opens a new one.
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and opens the file again without renaming it.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return w.open()
+}
+
+// Sync commits the current contents of the file to the stable storage.
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.ensureOpen(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file and waits for the background compression to finish.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.signalsCh != nil {
+		signal.Stop(w.signalsCh)
+		close(w.signalsCh)
+	}
+	close(w.millCh)
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+func (w *FileWriter) shouldRotate(n int64) bool {
+	if w.maxSize > 0 && w.size > 0 && w.size+n > w.maxSize {
+		return true
+	}
+	return w.interval > 0 && !time.Now().Before(w.rotateAt)
+}
+
+func (w *FileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("cannot open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot stat log file: %w", err)
+	}
+
+	w.file = f
+	w.size = info.Size()
+	if w.interval > 0 {
+		w.rotateAt = time.Now().Add(w.interval)
+	}
+	return nil
+}
+
+// ensureOpen opens the file again if the rotation or Reopen failed to open it.
+func (w *FileWriter) ensureOpen() error {
+	if w.file != nil {
+		return nil
+	}
+	return w.open()
+}
+
+// rotate renames the file and opens the new one. If the new file can't be opened,
+// the next write tries to open it again.
+func (w *FileWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	renameErr := os.Rename(w.path, w.backupName(time.Now()))
+	if err := w.open(); err != nil {
+		return err
+	}
+	if renameErr != nil && !errors.Is(renameErr, os.ErrNotExist) {
+		return fmt.Errorf("cannot rename log file: %w", renameErr)
+	}
+
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *FileWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	return filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+}
+
+func (w *FileWriter) nameParts() (dir, prefix, ext string) {
+	dir, name := filepath.Split(w.path)
+	ext = filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+func (w *FileWriter) watchSignals() {
+	defer w.wg.Done()
+
+	for range w.signalsCh {
+		if err := w.Reopen(); err != nil && !errors.Is(err, os.ErrClosed) {
+			fmt.Fprintf(os.Stderr, "cannot reopen log file: %s\n", err)
+		}
+	}
+}
+
+// mill compresses and removes the old rotated files in background.
+func (w *FileWriter) mill() {
+	defer w.wg.Done()
+
+	for range w.millCh {
+		if err := w.processBackups(); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot process rotated log files: %s\n", err)
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+func (w *FileWriter) processBackups() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		for _, b := range backups[w.maxBackups:] {
+			errs = append(errs, os.Remove(b.path))
+		}
+		backups = backups[:w.maxBackups]
+	}
+
+	if w.compress {
+		for _, b := range backups {
+			if !strings.HasSuffix(b.path, compressSuffix) {
+				errs = append(errs, compressFile(b.path))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups returns the rotated files sorted from the newest to the oldest.
+func (w *FileWriter) backups() ([]backupFile, error) {
+	dir, prefix, ext := w.nameParts()
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read log directory: %w", err)
+	}
+
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		ts := strings.TrimPrefix(strings.TrimSuffix(name, compressSuffix), prefix)
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(ts, ext))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+
+	slices.SortFunc(backups, func(a, b backupFile) int {
+		return b.time.Compare(a.time)
+	})
+	return backups, nil
+}
+
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644) //nolint:mnd
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = dst.Close()
+			_ = os.Remove(dst.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestFileWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rotate by size", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		w, err := NewFileWriter(filepath.Join(dir, "app.log"), WithFileMaxSize(10))
+		require.NoError(t, err)
+
+		// Act
+		_, _ = w.Write([]byte("12345678\n"))
+		_, _ = w.Write([]byte("abcdefgh\n"))
+		require.NoError(t, w.Close())
+
+		// Assert
+		names := listDir(t, dir)
+		require.Len(t, names, 2)
+		data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+		require.NoError(t, err)
+		assert.Equal(t, "abcdefgh\n", string(data))
+		assert.True(t, strings.HasPrefix(names[0], "app-"))
+		assert.True(t, strings.HasSuffix(names[0], ".log"))
+	})
+
+	t.Run("rotate by interval", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		w, err := NewFileWriter(filepath.Join(dir, "app.log"), WithFileRotationInterval(10*time.Millisecond))
+		require.NoError(t, err)
+
+		// Act
+		_, _ = w.Write([]byte("first\n"))
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("second\n"))
+		require.NoError(t, w.Close())
+
+		// Assert
+		assert.Len(t, listDir(t, dir), 2)
+	})
+
+	t.Run("max backups and compression", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		w, err := NewFileWriter(filepath.Join(dir, "app.log"), WithFileMaxBackups(2), WithFileCompression())
+		require.NoError(t, err)
+
+		// Act
+		for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+			_, _ = w.Write([]byte(line))
+			require.NoError(t, w.Rotate())
+		}
+		require.NoError(t, w.Close())
+
+		// Assert
+		names := listDir(t, dir)
+		require.Len(t, names, 3)
+		assert.Equal(t, "app.log", names[2])
+
+		f, err := os.Open(filepath.Join(dir, names[1]))
+		require.NoError(t, err)
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		data, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		assert.Equal(t, "4\n", string(data))
+	})
+
+	t.Run("open after failed rotation", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := filepath.Join(t.TempDir(), "logs")
+		require.NoError(t, os.Mkdir(dir, 0o755))
+		path := filepath.Join(dir, "app.log")
+		w, err := NewFileWriter(path, WithFileMaxSize(10))
+		require.NoError(t, err)
+		defer w.Close()
+		_, err = w.Write([]byte("12345678\n"))
+		require.NoError(t, err)
+
+		// Act
+		// The new file can't be opened while the directory is missing.
+		require.NoError(t, os.RemoveAll(dir))
+		_, rotateErr := w.Write([]byte("abcdefgh\n"))
+		_, missingErr := w.Write([]byte("abcdefgh\n"))
+		missingRotateErr := w.Rotate()
+		require.NoError(t, os.Mkdir(dir, 0o755))
+		restoreErr := w.Rotate()
+		_, err = w.Write([]byte("restored\n"))
+
+		// Assert
+		require.ErrorIs(t, rotateErr, os.ErrNotExist)
+		require.ErrorIs(t, missingErr, os.ErrNotExist)
+		require.ErrorIs(t, missingRotateErr, os.ErrNotExist)
+		require.NoError(t, restoreErr)
+		require.NoError(t, err)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "restored\n", string(data))
+	})
+
+	t.Run("reopen on signal", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		path := filepath.Join(dir, "app.log")
+		w, err := NewFileWriter(path, WithFileReopenSignal())
+		require.NoError(t, err)
+		defer w.Close()
+
+		// Act
+		require.NoError(t, os.Rename(path, path+".1"))
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+		// Assert
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("with plain text", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		dir := t.TempDir()
+		w, err := NewFileWriter(filepath.Join(dir, "app.log"))
+		require.NoError(t, err)
+		l := NewLogger(InfoLevel, WithPlainText(w))
+
+		// Act
+		l.Info(context.Background(), "infoMsg")
+		require.NoError(t, w.Close())
+
+		// Assert
+		data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "infoMsg")
+	})
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Field names of the entries written from the flight recorder.
+const (
+	BufferedFieldName     = "buffered"
+	BufferedTimeFieldName = "buffered_time"
+)
+
+type flightRecorderKey struct{}
+
+// flightRecorder is the ring buffer of the entries below the active level, see WithFlightRecorder.
+type flightRecorder struct {
+	mu      sync.Mutex
+	entries []bufferedEntry
+	next    int
+	full    bool
+}
+
+type bufferedEntry struct {
+	logger *Logger
+	level  Level
+	time   time.Time
+	caller string
+	msg    string
+	fields Fields
+}
+
+// WithFlightRecorder attaches the buffer of the specified size (in entries) to the context.
+//
+// The entries written with the context below the active level are kept in the buffer instead of
+// being discarded. When Error or FatalError is called with the context, or Dump is called explicitly,
+// the buffered entries are written in order before the error, marked with the "buffered" field
+// and the original time in the "buffered_time" field. When the buffer is full, the oldest entries are dropped.
+//
+// If no error happens, then the buffered entries are discarded together with the context.
+func WithFlightRecorder(ctx context.Context, size int) context.Context {
+	if size <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, flightRecorderKey{}, &flightRecorder{entries: make([]bufferedEntry, size)})
+}
+
+// Dump writes the entries buffered by the flight recorder of the context (see WithFlightRecorder)
+// and empties the buffer.
+func Dump(ctx context.Context) {
+	r, ok := ctx.Value(flightRecorderKey{}).(*flightRecorder)
+	if !ok {
+		return
+	}
+
+	for _, e := range r.drain() {
+		zl := e.logger.base().Level(zerolog.TraceLevel)
+		event := zl.WithLevel(zerolog.Level(e.level))
+		if e.caller != "" {
+			event = event.Str(zerolog.CallerFieldName, e.caller)
+		}
+		if e.logger.name != "" {
+			event = event.Str(LoggerFieldName, e.logger.name)
+		}
+		event.Fields([]any(e.fields)).
+			Bool(BufferedFieldName, true).
+			Time(BufferedTimeFieldName, e.time).
+			Msg(e.msg)
+	}
+}
+
+// buffered keeps the entry below the active level in the flight recorder of the context, if any.
+// Reports whether the entry is buffered.
+func (l *Logger) buffered(ctx context.Context, level Level, msg string, fields Fields) bool {
+	r := l.recorderFor(ctx, level)
+	if r == nil {
+		return false
+	}
+
+	var caller string
+	if callerEnabled.Load() {
+		// Skip this function, the logging method and the package function, as withFieldsAndCaller does.
+		if pc, file, line, ok := runtime.Caller(3); ok { //nolint:mnd
+			caller = zerolog.CallerMarshalFunc(pc, file, line)
+		}
+	}
+
+	l.buffer(ctx, r, level, caller, msg, fields)
+	return true
+}
+
+// recorderFor returns the flight recorder of the context if the entry of the level must be buffered.
+func (l *Logger) recorderFor(ctx context.Context, level Level) *flightRecorder {
+	r, ok := ctx.Value(flightRecorderKey{}).(*flightRecorder)
+	if !ok || level >= l.levelFor(ctx) {
+		return nil
+	}
+	return r
+}
+
+func (l *Logger) buffer(ctx context.Context, r *flightRecorder, level Level, caller, msg string, fields Fields) {
+	r.add(bufferedEntry{
+		logger: l,
+		level:  level,
+		time:   zerolog.TimestampFunc(),
+		caller: caller,
+		msg:    l.redactor.String(msg),
+		fields: l.eventFields(ctx, fields),
+	})
+}
+
+func (r *flightRecorder) add(e bufferedEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next, r.full = 0, true
+	}
+}
+
+// drain returns the buffered entries in order and empties the buffer.
+func (r *flightRecorder) drain() []bufferedEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []bufferedEntry
+	if r.full {
+		result = append(result, r.entries[r.next:]...)
+	}
+	result = append(result, r.entries[:r.next]...)
+
+	clear(r.entries)
+	r.next, r.full = 0, false
+	return result
+}
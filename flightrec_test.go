@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/cdnnow-pro/go-log/logtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlightRecorder_DumpOnError(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := WithFlightRecorder(rec.Context(context.Background(), InfoLevel), 10)
+	ctx = InjectFields(ctx, "request", "r1")
+
+	// Act
+	Debug(ctx, "first", "step", 1)
+	Info(ctx, "visible")
+	Debug(ctx, "second", "step", 2)
+	Error(ctx, errors.New("boom"), "failed")
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 4, entries.String())
+
+	assert.Equal(t, "visible", entries[0].Message)
+	assert.NotContains(t, entries[0].Fields, BufferedFieldName)
+
+	for i, msg := range []string{"first", "second"} {
+		e := entries[i+1]
+		assert.Equal(t, DebugLevel, e.Level)
+		assert.Equal(t, msg, e.Message)
+		assert.Equal(t, true, e.Fields[BufferedFieldName])
+		assert.Contains(t, e.Fields, BufferedTimeFieldName)
+		assert.Equal(t, float64(i+1), e.Fields["step"])
+		assert.Equal(t, "r1", e.Fields["request"])
+	}
+
+	assert.Equal(t, "failed", entries[3].Message)
+	assert.Equal(t, "boom", entries[3].Error)
+}
+
+func TestFlightRecorder_CallerFields(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := WithFlightRecorder(rec.Context(context.Background(), InfoLevel), 10)
+	fields := make([]any, 2, 4)
+	fields[0], fields[1] = "step", 1
+
+	// Act
+	DebugWithTrace(ctx, "traced", "stack", fields...)
+	Dump(ctx)
+
+	// Assert
+	// The fields passed by the caller aren't appended to in place.
+	assert.Equal(t, []any{"step", 1, nil, nil}, fields[:4])
+	entries := rec.Entries()
+	require.Len(t, entries, 1, entries.String())
+	assert.Equal(t, "stack", entries[0].Fields["trace"])
+}
+
+func TestFlightRecorder_Discard(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := WithFlightRecorder(rec.Context(context.Background(), InfoLevel), 10)
+
+	// Act
+	Debug(ctx, "detail")
+	Info(ctx, "done")
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 1, entries.String())
+	assert.Equal(t, "done", entries[0].Message)
+}
+
+func TestFlightRecorder_Dump(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := WithFlightRecorder(rec.Context(context.Background(), WarnLevel), 3)
+
+	// Act
+	for i := range 5 {
+		Info(ctx, fmt.Sprint("entry ", i))
+	}
+	Dump(ctx)
+	Dump(ctx)
+
+	// Assert
+	// The oldest entries are dropped when the buffer is full, and the buffer is emptied by the dump.
+	assert.Equal(t, []string{"entry 2", "entry 3", "entry 4"}, messages(rec.Entries()))
+}
+
+func TestFlightRecorder_Caller(t *testing.T) {
+	SetCallerEnabled(true)
+	t.Cleanup(func() { SetCallerEnabled(false) })
+
+	// Arrange
+	rec := logtest.New()
+	ctx := WithFlightRecorder(rec.Context(context.Background(), InfoLevel), 10)
+	ctx = ToContext(ctx, FromContext(ctx).Named("worker"))
+
+	// Act
+	Debug(ctx, "detail")
+	Dump(ctx)
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 1, entries.String())
+	assert.True(t, strings.Contains(entries[0].Caller, "flightrec_test.go"), entries[0].Caller)
+	assert.Equal(t, "worker", entries[0].Fields[LoggerFieldName])
+}
+
+func TestFlightRecorder_WithoutRecorder(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := rec.Context(context.Background(), InfoLevel)
+
+	// Act
+	Debug(ctx, "detail")
+	Error(ctx, errors.New("boom"), "failed")
+
+	// Assert
+	assert.Equal(t, []string{"failed"}, messages(rec.Entries()))
+}
+
+func messages(entries logtest.Entries) []string {
+	result := make([]string, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e.Message)
+	}
+	return result
+}
+
+func TestFlightRecorder_Slog(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := WithFlightRecorder(rec.Context(context.Background(), InfoLevel), 10)
+	l := slog.New(NewSlogHandler(FromContext(ctx)))
+
+	// Act
+	l.DebugContext(ctx, "detail", "step", 1)
+	l.InfoContext(ctx, "visible")
+	l.ErrorContext(ctx, "failed")
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 3, entries.String())
+	assert.Equal(t, "visible", entries[0].Message)
+	assert.Equal(t, "detail", entries[1].Message)
+	assert.Equal(t, DebugLevel, entries[1].Level)
+	assert.Equal(t, true, entries[1].Fields[BufferedFieldName])
+	assert.Equal(t, float64(1), entries[1].Fields["step"])
+	assert.Equal(t, "failed", entries[2].Message)
+}
@@ -0,0 +1,554 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	defaultFluentBatchSize     = 256
+	defaultFluentBatchWait     = time.Second
+	defaultFluentMaxBufferSize = 16 * 1024 * 1024
+	defaultFluentAckTimeout    = 10 * time.Second
+	defaultFluentDialTimeout   = 5 * time.Second
+	defaultFluentMinBackoff    = 100 * time.Millisecond
+	defaultFluentMaxBackoff    = 30 * time.Second
+)
+
+// ErrFluentShutdown is returned by FluentWriter after Shutdown.
+var ErrFluentShutdown = errors.New("fluent writer is shut down")
+
+// FluentMode is the event mode of the forward protocol.
+type FluentMode int8
+
+const (
+	// FluentPackedForward sends the events of a batch as a single binary of the concatenated MessagePack events.
+	FluentPackedForward FluentMode = iota
+	// FluentForward sends the events of a batch as a MessagePack array.
+	FluentForward
+)
+
+// FluentWriter is a zerolog.LevelWriter that sends the entries to Fluentd or Fluent Bit
+// with the forward protocol.
+//
+// Each entry becomes an event with the record of its fields and the time of its timestamp field
+// (the time of the write if there's none). The entries that aren't JSON (e.g. written by WithPlainText)
+// are sent as the record with the "message" field.
+//
+// The events are buffered and sent in batches from a background goroutine. When the connection fails,
+// the batch is kept in the buffer and resent after the reconnect, so with the ack mode (WithFluentAck)
+// no event is lost unless the buffer is full. Shutdown must be called to send the buffered events.
+type FluentWriter struct {
+	network       string
+	addr          string
+	tag           string
+	mode          FluentMode
+	ack           bool
+	ackTimeout    time.Duration
+	dialTimeout   time.Duration
+	batchSize     int
+	batchWait     time.Duration
+	maxBufferSize int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	errorHandler  func(error)
+
+	mu       sync.Mutex
+	closed   bool
+	events   [][]byte
+	buffered int
+	flushes  []chan struct{}
+
+	conn     net.Conn
+	stopConn func() bool
+	ready    chan struct{}
+	stop     chan struct{}
+	dropped  droppedCounter
+	ctx      context.Context //nolint:containedctx
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+type FluentOption func(*FluentWriter)
+
+// WithFluentTag sets the tag of the events (the executable name by default).
+func WithFluentTag(tag string) FluentOption {
+	return func(w *FluentWriter) {
+		w.tag = tag
+	}
+}
+
+// WithFluentMode sets the event mode (FluentPackedForward by default).
+func WithFluentMode(mode FluentMode) FluentOption {
+	return func(w *FluentWriter) {
+		w.mode = mode
+	}
+}
+
+// WithFluentAck enables the ack mode: every batch is sent with the "chunk" option
+// and is resent unless the server acknowledges it within the timeout (10 seconds by default).
+func WithFluentAck(timeout ...time.Duration) FluentOption {
+	return func(w *FluentWriter) {
+		w.ack = true
+		if len(timeout) > 0 && timeout[0] > 0 {
+			w.ackTimeout = timeout[0]
+		}
+	}
+}
+
+// WithFluentBatch sets the maximum number of events in a batch (256 by default)
+// and how long the events wait for the batch to fill (1 second by default).
+func WithFluentBatch(size int, wait time.Duration) FluentOption {
+	return func(w *FluentWriter) {
+		if size > 0 {
+			w.batchSize = size
+		}
+		if wait > 0 {
+			w.batchWait = wait
+		}
+	}
+}
+
+// WithFluentMaxBufferSize sets the size of the events in bytes buffered until they are sent (16 MiB by default).
+// The entries written when the buffer is full are dropped.
+func WithFluentMaxBufferSize(size int) FluentOption {
+	return func(w *FluentWriter) {
+		if size > 0 {
+			w.maxBufferSize = size
+		}
+	}
+}
+
+// WithFluentDialTimeout sets the timeout of the connection and of every write (5 seconds by default),
+// so a stalled server doesn't block the sending.
+func WithFluentDialTimeout(timeout time.Duration) FluentOption {
+	return func(w *FluentWriter) {
+		w.dialTimeout = timeout
+	}
+}
+
+// WithFluentReconnectBackoff sets the exponential backoff between the reconnects
+// (from 100 milliseconds up to 30 seconds by default).
+func WithFluentReconnectBackoff(minBackoff, maxBackoff time.Duration) FluentOption {
+	return func(w *FluentWriter) {
+		w.minBackoff = minBackoff
+		w.maxBackoff = maxBackoff
+	}
+}
+
+// WithFluentErrorHandler sets the function called when a batch can't be sent.
+// The entries dropped on the full buffer are reported periodically (every 10 seconds),
+// on the failed sends and on Shutdown.
+//
+// By default, the errors are printed to stderr.
+func WithFluentErrorHandler(f func(error)) FluentOption {
+	return func(w *FluentWriter) {
+		w.errorHandler = f
+	}
+}
+
+// NewFluentWriter creates a writer to the forward input of Fluentd or Fluent Bit and starts its background goroutine.
+//
+// The network is "tcp" (e.g. with the address "localhost:24224") or "unix". The connection is established
+// with the first batch, so the writer may be created before the server is up.
+func NewFluentWriter(network, addr string, opts ...FluentOption) (*FluentWriter, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return nil, fmt.Errorf("unsupported network of the forward protocol: %q", network)
+	}
+
+	w := &FluentWriter{
+		network:       network,
+		addr:          addr,
+		tag:           filepath.Base(os.Args[0]),
+		ackTimeout:    defaultFluentAckTimeout,
+		dialTimeout:   defaultFluentDialTimeout,
+		batchSize:     defaultFluentBatchSize,
+		batchWait:     defaultFluentBatchWait,
+		maxBufferSize: defaultFluentMaxBufferSize,
+		minBackoff:    defaultFluentMinBackoff,
+		maxBackoff:    defaultFluentMaxBackoff,
+		errorHandler: func(err error) {
+			_, _ = fmt.Fprintf(os.Stderr, "fluent: %v\n", err)
+		},
+		ready: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *FluentWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *FluentWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	event, err := fluentEvent(level, p, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrFluentShutdown
+	}
+	if w.buffered+len(event) > w.maxBufferSize {
+		w.dropped.drop()
+		return len(p), nil
+	}
+
+	w.events = append(w.events, event)
+	w.buffered += len(event)
+	if len(w.events) >= w.batchSize {
+		w.signal()
+	}
+	return len(p), nil
+}
+
+// Dropped returns the total number of the entries dropped on the full buffer or on Shutdown.
+func (w *FluentWriter) Dropped() uint64 {
+	return w.dropped.load()
+}
+
+// ForceFlush sends the buffered events and waits for them to be sent or the context to be done.
+func (w *FluentWriter) ForceFlush(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrFluentShutdown
+	}
+	w.flushes = append(w.flushes, flushed)
+	w.signal()
+	w.mu.Unlock()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown sends the buffered events, reconnecting as long as needed, and closes the connection.
+// If the context is done first, the unsent events are dropped and the context error is returned.
+//
+// Entries written after Shutdown are rejected with ErrFluentShutdown.
+func (w *FluentWriter) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.stop)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+// signal wakes up the background goroutine. Must be called with the lock held.
+func (w *FluentWriter) signal() {
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (w *FluentWriter) run() {
+	defer close(w.done)
+	defer w.cancel()
+
+	defer w.dropped.report(w.errorHandler, "buffer")
+
+	ticker := time.NewTicker(w.batchWait)
+	defer ticker.Stop()
+	report := time.NewTicker(dropReportInterval)
+	defer report.Stop()
+
+	for {
+		select {
+		case <-w.ready:
+			w.sendBuffered()
+		case <-ticker.C:
+			w.sendBuffered()
+		case <-report.C:
+			w.dropped.report(w.errorHandler, "buffer")
+		case <-w.stop:
+			w.sendBuffered()
+
+			w.mu.Lock()
+			w.dropped.add(len(w.events))
+			w.events = nil
+			w.buffered = 0
+			w.mu.Unlock()
+			w.closeConn()
+			return
+		}
+	}
+}
+
+// sendBuffered sends the buffered events batch by batch. A batch is removed from the buffer
+// only after it's sent (and acknowledged in the ack mode), otherwise it's resent after the reconnect.
+func (w *FluentWriter) sendBuffered() {
+	backoff := w.minBackoff
+	for {
+		w.mu.Lock()
+		batch := w.events[:min(len(w.events), w.batchSize)]
+		if len(batch) == 0 {
+			for _, flushed := range w.flushes {
+				close(flushed)
+			}
+			w.flushes = nil
+			w.mu.Unlock()
+			return
+		}
+		w.mu.Unlock()
+
+		if err := w.send(batch); err != nil {
+			w.errorHandler(fmt.Errorf("sending of %d log entries failed: %w", len(batch), err))
+			// The buffer fills up while the connection is down, so the drops are reported on the retries too.
+			w.dropped.report(w.errorHandler, "buffer")
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-w.ctx.Done():
+				timer.Stop()
+				return
+			}
+			backoff = min(backoff*2, w.maxBackoff) //nolint:mnd
+			continue
+		}
+		backoff = w.minBackoff
+
+		w.mu.Lock()
+		size := 0
+		for _, event := range batch {
+			size += len(event)
+		}
+		w.events = w.events[len(batch):]
+		w.buffered -= size
+		w.mu.Unlock()
+	}
+}
+
+// send writes the batch to the connection, dialing it if needed, and waits for the ack in the ack mode.
+func (w *FluentWriter) send(batch [][]byte) error {
+	var chunk string
+	if w.ack {
+		id := make([]byte, 16) //nolint:mnd
+		_, _ = rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+	}
+	msg, err := w.message(batch, chunk)
+	if err != nil {
+		return err
+	}
+
+	if w.conn == nil {
+		dialer := &net.Dialer{Timeout: w.dialTimeout}
+		conn, err := dialer.DialContext(w.ctx, w.network, w.addr)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		// Shutdown cancels the context when its own context is done, the blocked exchange is interrupted then.
+		w.stopConn = context.AfterFunc(w.ctx, func() { _ = conn.Close() })
+	}
+
+	if err := w.exchange(msg, chunk); err != nil {
+		w.closeConn()
+		return err
+	}
+	return nil
+}
+
+func (w *FluentWriter) closeConn() {
+	if w.conn == nil {
+		return
+	}
+	w.stopConn()
+	_ = w.conn.Close()
+	w.conn = nil
+}
+
+func (w *FluentWriter) exchange(msg []byte, chunk string) error {
+	if w.dialTimeout > 0 {
+		if err := w.conn.SetWriteDeadline(time.Now().Add(w.dialTimeout)); err != nil {
+			return err
+		}
+	}
+	if _, err := w.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	if err := w.conn.SetReadDeadline(time.Now().Add(w.ackTimeout)); err != nil {
+		return err
+	}
+	var resp struct {
+		Ack string `msgpack:"ack"`
+	}
+	if err := msgpack.NewDecoder(w.conn).Decode(&resp); err != nil {
+		return err
+	}
+	if resp.Ack != chunk {
+		return fmt.Errorf("unexpected ack %q for chunk %q", resp.Ack, chunk)
+	}
+	return nil
+}
+
+// message encodes the batch as [tag, entries, option] of the Forward or PackedForward mode.
+func (w *FluentWriter) message(batch [][]byte, chunk string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+
+	err := errors.Join(enc.EncodeArrayLen(3), enc.EncodeString(w.tag)) //nolint:mnd
+	if w.mode == FluentForward {
+		err = errors.Join(err, enc.EncodeArrayLen(len(batch)))
+		for _, event := range batch {
+			_, werr := buf.Write(event)
+			err = errors.Join(err, werr)
+		}
+	} else {
+		err = errors.Join(err, enc.EncodeBytes(bytes.Join(batch, nil)))
+	}
+
+	option := map[string]any{"size": len(batch)}
+	if chunk != "" {
+		option["chunk"] = chunk
+	}
+	err = errors.Join(err, enc.EncodeMapSorted(option))
+
+	return buf.Bytes(), err
+}
+
+// fluentEvent encodes the entry as the [time, record] event.
+func fluentEvent(level zerolog.Level, p []byte, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+
+	fields, err := parseJSONFields(p)
+	if err != nil {
+		fields = []jsonField{{key: zerolog.MessageFieldName, raw: jsonString(strings.TrimRight(string(p), "\n"))}}
+		if level != zerolog.NoLevel {
+			fields = append(fields, jsonField{key: zerolog.LevelFieldName, raw: jsonString(Level(level).String())})
+		}
+	}
+
+	t := now
+	for _, f := range fields {
+		if f.key != zerolog.TimestampFieldName {
+			continue
+		}
+		var s string
+		if json.Unmarshal(f.raw, &s) == nil {
+			if ts, err := time.Parse(zerolog.TimeFieldFormat, s); err == nil {
+				t = ts
+			}
+		}
+	}
+
+	err = errors.Join(enc.EncodeArrayLen(2), encodeFluentTime(enc, t), enc.EncodeMapLen(len(fields))) //nolint:mnd
+	for _, f := range fields {
+		err = errors.Join(err, enc.EncodeString(f.key), encodeFluentValue(enc, f.raw))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeFluentTime encodes the EventTime: the extension type 0 with the seconds and the nanoseconds.
+func encodeFluentTime(enc *msgpack.Encoder, t time.Time) error {
+	if err := enc.EncodeExtHeader(0, 8); err != nil { //nolint:mnd
+		return err
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[:4], uint32(t.Unix()))       //nolint:gosec
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond())) //nolint:gosec
+	_, err := enc.Writer().Write(b[:])
+	return err
+}
+
+// encodeFluentValue encodes the JSON value keeping the order of the object fields.
+func encodeFluentValue(enc *msgpack.Encoder, raw json.RawMessage) error {
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		return enc.EncodeString(s)
+	case 't', 'f':
+		return enc.EncodeBool(raw[0] == 't')
+	case 'n':
+		return enc.EncodeNil()
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return err
+		}
+		err := enc.EncodeArrayLen(len(items))
+		for _, item := range items {
+			err = errors.Join(err, encodeFluentValue(enc, item))
+		}
+		return err
+	case '{':
+		fields, err := parseJSONFields(raw)
+		if err != nil {
+			return err
+		}
+		err = enc.EncodeMapLen(len(fields))
+		for _, f := range fields {
+			err = errors.Join(err, enc.EncodeString(f.key), encodeFluentValue(enc, f.raw))
+		}
+		return err
+	default:
+		if i, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return enc.EncodeInt(i)
+		}
+		f, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return err
+		}
+		return enc.EncodeFloat64(f)
+	}
+}
@@ -0,0 +1,404 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type fluentEvent struct {
+	time   time.Time
+	record map[string]any
+}
+
+type fluentMessage struct {
+	tag    string
+	events []fluentEvent
+	option map[string]any
+}
+
+// forwardServer is a stand-in forward input sending the received messages to the channel.
+type forwardServer struct {
+	ln       net.Listener
+	messages chan fluentMessage
+	// ack tells whether to acknowledge the n-th received chunk.
+	ack func(n int) bool
+}
+
+func newForwardServer(t *testing.T, network, addr string, ack func(n int) bool) *forwardServer {
+	t.Helper()
+
+	ln, err := net.Listen(network, addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &forwardServer{ln: ln, messages: make(chan fluentMessage, 16), ack: ack}
+	go s.serve(t)
+	return s
+}
+
+func (s *forwardServer) serve(t *testing.T) {
+	var n atomic.Int32
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			dec := msgpack.NewDecoder(conn)
+			for {
+				msg, err := decodeForward(dec)
+				if err != nil {
+					return
+				}
+				received := n.Add(1)
+				s.messages <- msg
+
+				chunk, ok := msg.option["chunk"]
+				if !ok {
+					continue
+				}
+				if s.ack != nil && !s.ack(int(received)) {
+					return
+				}
+				resp, err := msgpack.Marshal(map[string]any{"ack": chunk})
+				assert.NoError(t, err)
+				_, _ = conn.Write(resp)
+			}
+		}()
+	}
+}
+
+func (s *forwardServer) receive(t *testing.T) fluentMessage {
+	t.Helper()
+
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return fluentMessage{}
+	}
+}
+
+func decodeForward(dec *msgpack.Decoder) (fluentMessage, error) {
+	var msg fluentMessage
+	if _, err := dec.DecodeArrayLen(); err != nil {
+		return msg, err
+	}
+	tag, err := dec.DecodeString()
+	if err != nil {
+		return msg, err
+	}
+	msg.tag = tag
+
+	code, err := dec.PeekCode()
+	if err != nil {
+		return msg, err
+	}
+	if code >= 0x90 && code <= 0x9f || code == 0xdc || code == 0xdd {
+		// Forward mode.
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return msg, err
+		}
+		for range n {
+			event, err := decodeFluentEvent(dec)
+			if err != nil {
+				return msg, err
+			}
+			msg.events = append(msg.events, event)
+		}
+	} else {
+		// PackedForward mode.
+		entries, err := dec.DecodeBytes()
+		if err != nil {
+			return msg, err
+		}
+		packed := msgpack.NewDecoder(bytes.NewReader(entries))
+		for {
+			event, err := decodeFluentEvent(packed)
+			if err != nil {
+				break
+			}
+			msg.events = append(msg.events, event)
+		}
+	}
+
+	msg.option, err = dec.DecodeMap()
+	return msg, err
+}
+
+func decodeFluentEvent(dec *msgpack.Decoder) (fluentEvent, error) {
+	var event fluentEvent
+	if _, err := dec.DecodeArrayLen(); err != nil {
+		return event, err
+	}
+	id, size, err := dec.DecodeExtHeader()
+	if err != nil {
+		return event, err
+	}
+	if id != 0 || size != 8 {
+		return event, errors.New("not EventTime")
+	}
+	var b [8]byte
+	if err := dec.ReadFull(b[:]); err != nil {
+		return event, err
+	}
+	event.time = time.Unix(int64(binary.BigEndian.Uint32(b[:4])), int64(binary.BigEndian.Uint32(b[4:])))
+	event.record, err = dec.DecodeMap()
+	return event, err
+}
+
+func TestFluentWriter_PackedForward(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	srv := newForwardServer(t, "tcp", "127.0.0.1:0", nil)
+	w, err := NewFluentWriter("tcp", srv.ln.Addr().String(), WithFluentTag("cdn.edge"))
+	require.NoError(t, err)
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "served", "status", 200, "ratio", 0.5, "cached", true,
+		"origin", map[string]any{"host": "o1"}, "tags", []string{"a", "b"})
+	l.Warn(context.Background(), "slow")
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	msg := srv.receive(t)
+	assert.Equal(t, "cdn.edge", msg.tag)
+	assert.Equal(t, map[string]any{"size": int8(2)}, msg.option)
+	require.Len(t, msg.events, 2)
+	assert.WithinDuration(t, time.Now(), msg.events[0].time, 5*time.Second)
+
+	record := msg.events[0].record
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "served", record["message"])
+	assert.EqualValues(t, 200, record["status"])
+	assert.InDelta(t, 0.5, record["ratio"], 0)
+	assert.Equal(t, true, record["cached"])
+	assert.Equal(t, map[string]any{"host": "o1"}, record["origin"])
+	assert.Equal(t, []any{"a", "b"}, record["tags"])
+	assert.Equal(t, "slow", msg.events[1].record["message"])
+}
+
+func TestFluentWriter_Forward(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	path := filepath.Join(t.TempDir(), "forward.sock")
+	srv := newForwardServer(t, "unix", path, nil)
+	w, err := NewFluentWriter("unix", path, WithFluentMode(FluentForward), WithFluentTag("app"))
+	require.NoError(t, err)
+
+	// Act
+	_, err = w.Write([]byte(`{"level":"DEBUG","time":"2024-05-01T10:00:02.500Z","message":"raw"}` + "\n"))
+	require.NoError(t, err)
+	_, err = w.WriteLevel(zerolog.ErrorLevel, []byte("plain text\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	msg := srv.receive(t)
+	assert.Equal(t, "app", msg.tag)
+	require.Len(t, msg.events, 2)
+	assert.True(t, time.Date(2024, 5, 1, 10, 0, 2, 500_000_000, time.UTC).Equal(msg.events[0].time))
+	assert.Equal(t, "raw", msg.events[0].record["message"])
+	assert.Equal(t, map[string]any{"message": "plain text", "level": "ERROR"}, msg.events[1].record)
+}
+
+func TestFluentWriter_Ack(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	// The first chunk isn't acknowledged: the server closes the connection.
+	srv := newForwardServer(t, "tcp", "127.0.0.1:0", func(n int) bool { return n > 1 })
+	w, err := NewFluentWriter("tcp", srv.ln.Addr().String(), WithFluentAck(time.Second),
+		WithFluentReconnectBackoff(time.Millisecond, 10*time.Millisecond), WithFluentErrorHandler(func(error) {}))
+	require.NoError(t, err)
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "acked")
+	require.NoError(t, w.ForceFlush(context.Background()))
+
+	// Assert
+	first := srv.receive(t)
+	second := srv.receive(t)
+	assert.NotEmpty(t, first.option["chunk"])
+	assert.NotEqual(t, first.option["chunk"], second.option["chunk"])
+	require.Len(t, second.events, 1)
+	assert.Equal(t, "acked", second.events[0].record["message"])
+	require.NoError(t, w.Shutdown(context.Background()))
+	assert.Zero(t, w.Dropped())
+}
+
+func TestFluentWriter_Reconnect(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	// Nothing listens on the address until the server is started.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	w, err := NewFluentWriter("tcp", addr, WithFluentAck(time.Second),
+		WithFluentReconnectBackoff(time.Millisecond, 10*time.Millisecond), WithFluentErrorHandler(func(error) {}))
+	require.NoError(t, err)
+	l := NewLogger(DebugLevel, WithOutput(w))
+	l.Info(context.Background(), "buffered")
+
+	// Act
+	srv := newForwardServer(t, "tcp", addr, nil)
+	require.NoError(t, w.ForceFlush(context.Background()))
+
+	// Assert
+	msg := srv.receive(t)
+	require.Len(t, msg.events, 1)
+	assert.Equal(t, "buffered", msg.events[0].record["message"])
+	require.NoError(t, w.Shutdown(context.Background()))
+}
+
+func TestFluentWriter_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	w, err := NewFluentWriter("unix", filepath.Join(t.TempDir(), "none.sock"),
+		WithFluentReconnectBackoff(time.Millisecond, 10*time.Millisecond), WithFluentErrorHandler(func(error) {}))
+	require.NoError(t, err)
+	l := NewLogger(DebugLevel, WithOutput(w))
+	l.Info(context.Background(), "lost")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err = w.Shutdown(ctx)
+
+	// Assert
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, uint64(1), w.Dropped())
+	_, err = w.Write([]byte(`{"message":"late"}`))
+	assert.ErrorIs(t, err, ErrFluentShutdown)
+}
+
+func TestNewFluentWriter_UnsupportedNetwork(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	_, err := NewFluentWriter("udp", "127.0.0.1:24224")
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestFluentWriter_DroppedReport(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	var (
+		mu      sync.Mutex
+		reports []string
+	)
+	w, err := NewFluentWriter("unix", filepath.Join(t.TempDir(), "none.sock"), WithFluentMaxBufferSize(100),
+		WithFluentReconnectBackoff(time.Hour, time.Hour), WithFluentErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if strings.Contains(err.Error(), "dropped") {
+				reports = append(reports, err.Error())
+			}
+		}))
+	require.NoError(t, err)
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	for range 10 {
+		l.Info(context.Background(), "flood")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_ = w.Shutdown(ctx)
+
+	// Assert
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, reports)
+	assert.Contains(t, reports[len(reports)-1], "buffer is full, ")
+}
+
+func TestFluentWriter_WriteTimeout(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	errs := make(chan error, 16)
+	w, err := NewFluentWriter("tcp", listenStalled(t), WithFluentDialTimeout(50*time.Millisecond),
+		WithFluentReconnectBackoff(time.Hour, time.Hour), WithFluentErrorHandler(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}))
+	require.NoError(t, err)
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	// The event doesn't fit the socket buffers, so the write blocks until the deadline.
+	l.Info(context.Background(), "large", "payload", strings.Repeat("x", 8<<20))
+
+	// Assert
+	select {
+	case err := <-errs:
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+	case <-time.After(5 * time.Second):
+		t.Fatal("the write isn't timed out")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_ = w.Shutdown(ctx)
+}
+
+func TestFluentWriter_ShutdownStalled(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	w, err := NewFluentWriter("tcp", listenStalled(t), WithFluentDialTimeout(time.Hour),
+		WithFluentErrorHandler(func(error) {}))
+	require.NoError(t, err)
+	l := NewLogger(DebugLevel, WithOutput(w))
+	l.Info(context.Background(), "large", "payload", strings.Repeat("x", 8<<20))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Act
+	done := make(chan error, 1)
+	go func() { done <- w.Shutdown(ctx) }()
+
+	// Assert
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown hangs on the stalled connection")
+	}
+}
@@ -0,0 +1,333 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// GELFVersion is the version of the GELF payload.
+const GELFVersion = "1.1"
+
+const (
+	// DefaultGELFChunkSize is the default maximum size of the UDP datagram, fitting the usual WAN MTU.
+	DefaultGELFChunkSize = 1420
+	maxGELFChunks        = 128
+	gelfChunkHeaderSize  = 12
+	defaultGELFDialTO    = 5 * time.Second
+	defaultGELFRetry     = time.Second
+)
+
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+// GELFCompression is the compression of the GELF messages sent over UDP.
+type GELFCompression int8
+
+const (
+	// GELFGzip compresses the messages with gzip.
+	GELFGzip GELFCompression = iota
+	// GELFZlib compresses the messages with zlib.
+	GELFZlib
+	// GELFNoCompression sends the messages uncompressed.
+	GELFNoCompression
+)
+
+// GELFWriter writes the entries to Graylog in the GELF 1.1 format.
+//
+// The level is mapped to the syslog severity, the message is sent as "short_message"
+// and the rest of the fields as the additional fields prefixed with "_" (e.g. "request_id" as "_request_id").
+//
+// Over UDP the messages are compressed and split into chunks if they don't fit a datagram.
+// Over TCP the messages are uncompressed and delimited with the null byte, and the connection
+// is re-established on the write failure. While the server is unreachable, the writes fail fast
+// with the last connection error until the retry delay passes.
+type GELFWriter struct {
+	network     string
+	raddr       string
+	host        string
+	compression GELFCompression
+	chunkSize   int
+	dialTimeout time.Duration
+	retryDelay  time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	dialErr error
+	retryAt time.Time
+}
+
+type GELFOption func(*GELFWriter)
+
+// WithGELFHost sets the "host" of the messages (os.Hostname by default).
+func WithGELFHost(host string) GELFOption {
+	return func(w *GELFWriter) {
+		w.host = host
+	}
+}
+
+// WithGELFCompression sets the compression of the UDP messages (GELFGzip by default).
+func WithGELFCompression(compression GELFCompression) GELFOption {
+	return func(w *GELFWriter) {
+		w.compression = compression
+	}
+}
+
+// WithGELFChunkSize sets the maximum size of the UDP datagram (DefaultGELFChunkSize by default).
+// Use 8192 in the networks with the jumbo frames.
+func WithGELFChunkSize(size int) GELFOption {
+	return func(w *GELFWriter) {
+		if size > gelfChunkHeaderSize {
+			w.chunkSize = size
+		}
+	}
+}
+
+// WithGELFDialTimeout sets the timeout of the connection and of every TCP write (5 seconds by default),
+// so a stalled server doesn't block the logging.
+func WithGELFDialTimeout(timeout time.Duration) GELFOption {
+	return func(w *GELFWriter) {
+		w.dialTimeout = timeout
+	}
+}
+
+// WithGELFRetryDelay sets the delay of the reconnection after the failed one (1 second by default).
+func WithGELFRetryDelay(delay time.Duration) GELFOption {
+	return func(w *GELFWriter) {
+		w.retryDelay = delay
+	}
+}
+
+// NewGELFWriter connects to the GELF input of Graylog and creates a writer.
+//
+// The network is "udp" (e.g. with the address "graylog:12201") or "tcp".
+func NewGELFWriter(network, raddr string, opts ...GELFOption) (*GELFWriter, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network of GELF: %q", network)
+	}
+
+	w := &GELFWriter{
+		network:     network,
+		raddr:       raddr,
+		compression: GELFGzip,
+		chunkSize:   DefaultGELFChunkSize,
+		dialTimeout: defaultGELFDialTO,
+		retryDelay:  defaultGELFRetry,
+	}
+	w.host, _ = os.Hostname()
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes the entry with the severity mapped from its "level" field.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel writes the entry with the severity mapped from the level.
+func (w *GELFWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	msg, err := w.format(level, p, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if err := w.send(msg); err == nil {
+			return len(p), nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	// Reconnect and retry once, but don't redial the unreachable server on every write.
+	if time.Now().Before(w.retryAt) {
+		return 0, w.dialErr
+	}
+	if err := w.connect(); err != nil {
+		w.dialErr = err
+		w.retryAt = time.Now().Add(w.retryDelay)
+		return 0, err
+	}
+	if err := w.send(msg); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection.
+func (w *GELFWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *GELFWriter) connect() error {
+	conn, err := net.DialTimeout(w.network, w.raddr, w.dialTimeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *GELFWriter) send(msg []byte) error {
+	if _, ok := w.conn.(*net.UDPConn); !ok {
+		if w.dialTimeout > 0 {
+			if err := w.conn.SetWriteDeadline(time.Now().Add(w.dialTimeout)); err != nil {
+				return err
+			}
+		}
+		_, err := w.conn.Write(append(msg, 0))
+		return err
+	}
+
+	msg, err := w.compress(msg)
+	if err != nil {
+		return err
+	}
+	if len(msg) <= w.chunkSize {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+
+	// Chunked GELF: the magic bytes, the message ID, the sequence number and count, and the data.
+	dataSize := w.chunkSize - gelfChunkHeaderSize
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > maxGELFChunks {
+		return fmt.Errorf("gelf message of %d bytes needs more than %d chunks", len(msg), maxGELFChunks)
+	}
+
+	id := make([]byte, 8) //nolint:mnd
+	_, _ = rand.Read(id)
+	chunk := make([]byte, 0, w.chunkSize)
+	for i := range count {
+		data := msg[i*dataSize : min((i+1)*dataSize, len(msg))]
+		chunk = append(append(append(chunk[:0], gelfChunkMagic...), id...), byte(i), byte(count))
+		chunk = append(chunk, data...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *GELFWriter) compress(msg []byte) ([]byte, error) {
+	switch w.compression {
+	case GELFGzip:
+		return gzipBytes(msg)
+	case GELFZlib:
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(msg); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return msg, nil
+	}
+}
+
+// format returns the GELF payload of the entry.
+func (w *GELFWriter) format(level zerolog.Level, p []byte, now time.Time) ([]byte, error) {
+	msg := map[string]any{
+		"version": GELFVersion,
+		"host":    w.host,
+	}
+	timestamp := now
+
+	fields, err := parseJSONFields(p)
+	if err != nil {
+		msg["short_message"] = strings.TrimRight(string(p), "\n")
+	}
+	for _, f := range fields {
+		switch f.key {
+		case zerolog.LevelFieldName:
+			if level == zerolog.NoLevel {
+				_, level = levelText(f.raw)
+			}
+		case zerolog.TimestampFieldName:
+			var s string
+			if json.Unmarshal(f.raw, &s) == nil {
+				if t, err := time.Parse(zerolog.TimeFieldFormat, s); err == nil {
+					timestamp = t
+				}
+			}
+		case zerolog.MessageFieldName:
+			msg["short_message"], _ = logfmtValue(f.raw)
+		default:
+			msg[gelfFieldName(f.key)] = gelfValue(f.raw)
+		}
+	}
+
+	// short_message is required.
+	if s, _ := msg["short_message"].(string); s == "" {
+		msg["short_message"] = "-"
+	}
+	msg["level"] = int(levelToSyslogPriority(Level(level)))
+	msg["timestamp"] = json.Number(fmt.Sprintf("%d.%03d", timestamp.Unix(), timestamp.Nanosecond()/int(time.Millisecond)))
+
+	return json.Marshal(msg)
+}
+
+// gelfValue returns the number as is and the rest of the values as the strings.
+func gelfValue(raw json.RawMessage) any {
+	switch raw[0] {
+	case '"', 't', 'f', 'n', '{', '[':
+		value, err := logfmtValue(raw)
+		if err != nil {
+			return string(raw)
+		}
+		return value
+	default:
+		return json.Number(raw)
+	}
+}
+
+// gelfFieldName returns the additional field name: "_" followed by letters, digits, '_', '.' and '-'.
+// "_id" is reserved by Graylog, so "id" is sent as "_id_".
+func gelfFieldName(key string) string {
+	name := "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "_id" {
+		return "_id_"
+	}
+	return name
+}
@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listenGELF(t *testing.T) net.PacketConn {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pc.Close() })
+	return pc
+}
+
+func decodeGELF(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+
+	var msg map[string]any
+	require.NoError(t, json.Unmarshal(data, &msg), string(data))
+	return msg
+}
+
+func TestGELFWriter_UDP(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	pc := listenGELF(t)
+	w, err := NewGELFWriter("udp", pc.LocalAddr().String(), WithGELFHost("edge-1"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutput(w))
+
+	// Act
+	l.Error(context.Background(), errors.New("boom"), "purge failed",
+		"id", "p1", "http.path", "/img", "objects", 3, "cached", false, "origin", map[string]any{"host": "o1"})
+
+	// Assert
+	zr, err := gzip.NewReader(strings.NewReader(readDatagram(t, pc)))
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	msg := decodeGELF(t, data)
+	assert.Equal(t, "1.1", msg["version"])
+	assert.Equal(t, "edge-1", msg["host"])
+	assert.Equal(t, "purge failed", msg["short_message"])
+	assert.InDelta(t, 3, msg["level"], 0)
+	assert.InDelta(t, float64(time.Now().Unix()), msg["timestamp"], 5)
+	assert.Equal(t, "boom", msg["_error"])
+	assert.Equal(t, "p1", msg["_id_"])
+	assert.Equal(t, "/img", msg["_http.path"])
+	assert.InDelta(t, 3, msg["_objects"], 0)
+	assert.Equal(t, "false", msg["_cached"])
+	assert.Equal(t, `{"host":"o1"}`, msg["_origin"])
+	assert.NotContains(t, msg, "_level")
+	assert.NotContains(t, msg, "_time")
+	assert.NotContains(t, msg, "_message")
+}
+
+func TestGELFWriter_Chunked(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	pc := listenGELF(t)
+	w, err := NewGELFWriter("udp", pc.LocalAddr().String(),
+		WithGELFCompression(GELFNoCompression), WithGELFChunkSize(200))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithOutput(w))
+	payload := strings.Repeat("x", 1000)
+
+	// Act
+	l.Warn(context.Background(), "large", "payload", payload)
+
+	// Assert
+	var (
+		id    string
+		count int
+		data  []byte
+	)
+	for seq := 0; seq == 0 || seq < count; seq++ {
+		chunk := []byte(readDatagram(t, pc))
+		require.LessOrEqual(t, len(chunk), 200)
+		require.Equal(t, []byte{0x1e, 0x0f}, chunk[:2])
+		if seq == 0 {
+			id, count = string(chunk[2:10]), int(chunk[11])
+		}
+		assert.Equal(t, id, string(chunk[2:10]))
+		assert.Equal(t, seq, int(chunk[10]))
+		data = append(data, chunk[12:]...)
+	}
+	assert.Equal(t, 6, count)
+
+	msg := decodeGELF(t, data)
+	assert.Equal(t, "large", msg["short_message"])
+	assert.InDelta(t, 4, msg["level"], 0)
+	assert.Equal(t, payload, msg["_payload"])
+}
+
+func TestGELFWriter_Zlib(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	pc := listenGELF(t)
+	w, err := NewGELFWriter("udp", pc.LocalAddr().String(), WithGELFCompression(GELFZlib))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+
+	// Act
+	_, err = w.Write([]byte(`{"level":"DEBUG","time":"2024-05-01T10:00:02.500Z","message":"raw"}` + "\n"))
+
+	// Assert
+	require.NoError(t, err)
+	zr, err := zlib.NewReader(strings.NewReader(readDatagram(t, pc)))
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"timestamp":1714557602.500`)
+
+	msg := decodeGELF(t, data)
+	assert.Equal(t, "raw", msg["short_message"])
+	assert.InDelta(t, 7, msg["level"], 0)
+}
+
+func TestGELFWriter_TCP(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	w, err := NewGELFWriter("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "first")
+	l.Info(context.Background(), "second")
+
+	// Assert
+	conn := <-conns
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	r := bufio.NewReader(conn)
+	for _, expected := range []string{"first", "second"} {
+		frame, err := r.ReadBytes(0)
+		require.NoError(t, err)
+		msg := decodeGELF(t, bytes.TrimSuffix(frame, []byte{0}))
+		assert.Equal(t, expected, msg["short_message"])
+		assert.InDelta(t, 6, msg["level"], 0)
+	}
+}
+
+func TestGELFWriter_WriteTimeout(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	w, err := NewGELFWriter("tcp", listenStalled(t), WithGELFDialTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	// The message doesn't fit the socket buffers, so the write blocks until the deadline.
+	payload := strings.Repeat("x", 8<<20)
+
+	// Act
+	_, err = w.Write([]byte(`{"level":"INFO","message":"` + payload + `"}`))
+
+	// Assert
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
+
+func TestGELFWriter_RetryDelay(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	w, err := NewGELFWriter("tcp", addr, WithGELFRetryDelay(300*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.NoError(t, ln.Close())
+	entry := []byte(`{"level":"info","message":"retried"}`)
+	require.Eventually(t, func() bool {
+		_, err := w.Write(entry)
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	// Act
+	_, err = w.Write(entry)
+
+	// Assert
+	require.Error(t, err, "the server is redialed before the retry delay")
+	assert.Eventually(t, func() bool {
+		_, err := w.Write(entry)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestNewGELFWriter_UnsupportedNetwork(t *testing.T) {
+	t.Parallel()
+
+	// Act
+	_, err := NewGELFWriter("unix", "/tmp/gelf.sock")
+
+	// Assert
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultRequestIDHeader is the default name of the header (or gRPC metadata key) with the request ID.
+const DefaultRequestIDHeader = "x-request-id"
+
+// Field names injected by the interceptors.
+const (
+	RequestIDFieldName    = "request_id"
+	PeerAddressFieldName  = "peer.address"
+	GrpcServiceFieldName  = "grpc.service"
+	GrpcMethodFieldName   = "grpc.method"
+	GrpcCodeFieldName     = "grpc.code"
+	GrpcDurationFieldName = "grpc.duration"
+)
+
+type interceptorConfig struct {
+	logger          *Logger
+	requestIDHeader string
+	codeLevel       func(codes.Code) Level
+}
+
+type InterceptorOption func(*interceptorConfig)
+
+// WithInterceptorLogger sets the logger stored to the context of the calls.
+// By default, the logger from the call context (see FromContext) is used.
+func WithInterceptorLogger(l *Logger) InterceptorOption {
+	return func(c *interceptorConfig) {
+		c.logger = l
+	}
+}
+
+// WithInterceptorRequestIDHeader sets the metadata key with the request ID (DefaultRequestIDHeader by default).
+func WithInterceptorRequestIDHeader(key string) InterceptorOption {
+	return func(c *interceptorConfig) {
+		c.requestIDHeader = strings.ToLower(key)
+	}
+}
+
+// WithInterceptorCodeLevel sets the function that selects the level of the completion entry
+// by the status code of the call (DefaultCodeLevel by default).
+func WithInterceptorCodeLevel(f func(codes.Code) Level) InterceptorOption {
+	return func(c *interceptorConfig) {
+		c.codeLevel = f
+	}
+}
+
+// DefaultCodeLevel maps the client errors to InfoLevel, the errors which usually need attention
+// to WarnLevel and the server errors to ErrorLevel.
+func DefaultCodeLevel(code codes.Code) Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.Unauthenticated:
+		return InfoLevel
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition,
+		codes.Aborted, codes.OutOfRange, codes.Unavailable:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+func newInterceptorConfig(opts []InterceptorOption) *interceptorConfig {
+	c := &interceptorConfig{
+		requestIDHeader: DefaultRequestIDHeader,
+		codeLevel:       DefaultCodeLevel,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// UnaryServerInterceptor injects the call fields (service, method, peer address and request ID) into the context,
+// stores the logger to the context and writes the completion entry with the status code and the duration.
+//
+// The request ID is taken from the incoming metadata or generated.
+func UnaryServerInterceptor(opts ...InterceptorOption) grpc.UnaryServerInterceptor {
+	c := newInterceptorConfig(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = c.serverContext(ctx, info.FullMethod)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		c.logCompletion(ctx, "finished unary call", start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the stream counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(opts ...InterceptorOption) grpc.StreamServerInterceptor {
+	c := newInterceptorConfig(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := c.serverContext(ss.Context(), info.FullMethod)
+
+		start := time.Now()
+		err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+		c.logCompletion(ctx, "finished streaming call", start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor injects the call fields (service, method, target and request ID) into the context,
+// stores the logger to the context and writes the completion entry with the status code and the duration.
+//
+// The request ID is taken from the outgoing metadata or the context fields and propagated to the server.
+func UnaryClientInterceptor(opts ...InterceptorOption) grpc.UnaryClientInterceptor {
+	c := newInterceptorConfig(opts)
+	return func(
+		ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		ctx = c.clientContext(ctx, method, cc)
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		c.logCompletion(ctx, "finished unary call", start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor is the stream counterpart of UnaryClientInterceptor.
+//
+// The completion entry is written once the stream is finished: when RecvMsg returns io.EOF or an error,
+// or the response of the stream without the server streaming is received.
+func StreamClientInterceptor(opts ...InterceptorOption) grpc.StreamClientInterceptor {
+	c := newInterceptorConfig(opts)
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx = c.clientContext(ctx, method, cc)
+
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			c.logCompletion(ctx, "finished streaming call", start, err)
+			return nil, err
+		}
+		return &loggingClientStream{ClientStream: cs, serverStreams: desc.ServerStreams, finish: func(err error) {
+			c.logCompletion(ctx, "finished streaming call", start, err)
+		}}, nil
+	}
+}
+
+// loggingClientStream writes the completion entry of the wrapped client stream once it's finished.
+type loggingClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	finish        func(err error)
+}
+
+func (s *loggingClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.once.Do(func() { s.finish(nil) })
+	case err != nil, !s.serverStreams:
+		s.once.Do(func() { s.finish(err) })
+	}
+	return err
+}
+
+func (c *interceptorConfig) serverContext(ctx context.Context, fullMethod string) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		requestID = firstMetadataValue(md, c.requestIDHeader)
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+
+	service, method := splitFullMethod(fullMethod)
+	fields := Fields{
+		GrpcServiceFieldName, service,
+		GrpcMethodFieldName, method,
+		RequestIDFieldName, requestID,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, PeerAddressFieldName, p.Addr.String())
+	}
+
+	return c.withLogger(InjectFields(ctx, fields...))
+}
+
+func (c *interceptorConfig) clientContext(ctx context.Context, fullMethod string, cc *grpc.ClientConn) context.Context {
+	var requestID string
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		requestID = firstMetadataValue(md, c.requestIDHeader)
+	}
+	if requestID == "" {
+		if requestID = requestIDFromContext(ctx); requestID == "" {
+			requestID = newRequestID()
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, c.requestIDHeader, requestID)
+	}
+
+	service, method := splitFullMethod(fullMethod)
+	fields := Fields{
+		GrpcServiceFieldName, service,
+		GrpcMethodFieldName, method,
+		RequestIDFieldName, requestID,
+	}
+	if cc != nil {
+		fields = append(fields, PeerAddressFieldName, cc.Target())
+	}
+
+	return c.withLogger(InjectFields(ctx, fields...))
+}
+
+func (c *interceptorConfig) withLogger(ctx context.Context) context.Context {
+	if c.logger != nil {
+		return ToContext(ctx, c.logger)
+	}
+	return ToContext(ctx, FromContext(ctx))
+}
+
+func (c *interceptorConfig) logCompletion(ctx context.Context, msg string, start time.Time, err error) {
+	code := status.Code(err)
+	FromContext(ctx).write(ctx, c.codeLevel(code), err, msg,
+		GrpcCodeFieldName, code.String(),
+		GrpcDurationFieldName, time.Since(start),
+	)
+}
+
+// splitFullMethod splits "/package.Service/Method" into the service and the method names.
+func splitFullMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+// requestIDFromContext returns the request ID injected into the context fields.
+func requestIDFromContext(ctx context.Context) string {
+	i := ExtractFields(ctx).Iterator()
+	for k, v, ok := i.Next(); ok; k, v, ok = i.Next() {
+		if k == RequestIDFieldName {
+			if s, ok := v.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
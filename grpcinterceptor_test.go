@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fields and completion", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", "req-1"))
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}})
+		interceptor := UnaryServerInterceptor()
+		info := &grpc.UnaryServerInfo{FullMethod: "/cdn.Cache/Purge"}
+
+		// Act
+		_, err := interceptor(ctx, nil, info, func(ctx context.Context, _ any) (any, error) {
+			Info(ctx, "handled")
+			return nil, status.Error(codes.Internal, "boom")
+		})
+
+		// Assert
+		require.Error(t, err)
+		lines := splitLines(buf.Bytes())
+		require.Len(t, lines, 2)
+
+		handled := decodeEntry(t, lines[0])
+		assert.Equal(t, "cdn.Cache", handled["grpc.service"])
+		assert.Equal(t, "Purge", handled["grpc.method"])
+		assert.Equal(t, "req-1", handled["request_id"])
+		assert.Equal(t, "10.0.0.1:5000", handled["peer.address"])
+
+		completion := decodeEntry(t, lines[1])
+		assert.Equal(t, ErrorLevel.String(), completion["level"])
+		assert.Equal(t, "Internal", completion["grpc.code"])
+		assert.Contains(t, completion, "grpc.duration")
+		assert.Contains(t, completion["error"], "boom")
+	})
+
+	t.Run("code level", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		interceptor := UnaryServerInterceptor(WithInterceptorCodeLevel(func(code codes.Code) Level {
+			if code == codes.OK {
+				return DebugLevel
+			}
+			return WarnLevel
+		}))
+
+		// Act
+		_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/cdn.Cache/Get"},
+			func(context.Context, any) (any, error) { return nil, nil })
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, DebugLevel.String(), entry["level"])
+		assert.Equal(t, "OK", entry["grpc.code"])
+		assert.NotEmpty(t, entry["request_id"])
+	})
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx, buf := getTestData()
+	ctx = InjectFields(ctx, "request_id", "req-2")
+	interceptor := UnaryClientInterceptor()
+
+	// Act
+	var requestID []string
+	err := interceptor(ctx, "/cdn.Cache/Get", nil, nil, nil,
+		func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			requestID = md.Get("x-request-id")
+			return nil
+		})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-2"}, requestID)
+	entry := decodeEntry(t, buf.Bytes())
+	assert.Equal(t, "Get", entry["grpc.method"])
+	assert.Equal(t, "OK", entry["grpc.code"])
+}
+
+// recvClientStream returns the errors from RecvMsg in order.
+type recvClientStream struct {
+	grpc.ClientStream
+	errs []error
+}
+
+func (s *recvClientStream) RecvMsg(any) error {
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		desc     *grpc.StreamDesc
+		errs     []error
+		wantCode string
+	}{
+		{
+			name:     "server streaming",
+			desc:     &grpc.StreamDesc{ServerStreams: true},
+			errs:     []error{nil, nil, io.EOF},
+			wantCode: "OK",
+		},
+		{
+			name:     "server streaming error",
+			desc:     &grpc.StreamDesc{ServerStreams: true},
+			errs:     []error{nil, status.Error(codes.Unavailable, "gone")},
+			wantCode: "Unavailable",
+		},
+		{
+			name:     "client streaming",
+			desc:     &grpc.StreamDesc{ClientStreams: true},
+			errs:     []error{nil},
+			wantCode: "OK",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			ctx, buf := getTestData()
+			interceptor := StreamClientInterceptor()
+			cs, err := interceptor(ctx, tt.desc, nil, "/cdn.Cache/Watch",
+				func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+					return &recvClientStream{errs: tt.errs}, nil
+				})
+			require.NoError(t, err)
+			assert.Empty(t, buf.String())
+
+			// Act
+			for range tt.errs {
+				_ = cs.RecvMsg(nil)
+			}
+
+			// Assert
+			lines := splitLines(buf.Bytes())
+			require.Len(t, lines, 1)
+			entry := decodeEntry(t, lines[0])
+			assert.Equal(t, "finished streaming call", entry["message"])
+			assert.Equal(t, "Watch", entry["grpc.method"])
+			assert.Equal(t, tt.wantCode, entry["grpc.code"])
+			assert.Contains(t, entry, "grpc.duration")
+		})
+	}
+
+	t.Run("failed to start", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		interceptor := StreamClientInterceptor()
+
+		// Act
+		_, err := interceptor(ctx, &grpc.StreamDesc{}, nil, "/cdn.Cache/Watch",
+			func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+				return nil, status.Error(codes.Unavailable, "no connection")
+			})
+
+		// Assert
+		require.Error(t, err)
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, "Unavailable", entry["grpc.code"])
+	})
+}
@@ -5,20 +5,40 @@ package log
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc/grpclog"
 )
 
+// GrpcComponentFieldName is the field name for the gRPC component (e.g. "transport").
+const GrpcComponentFieldName = "grpc.component"
+
+// grpcVerbosityEnv is the environment variable gRPC uses to set the verbosity of its default logger.
+const grpcVerbosityEnv = "GRPC_GO_LOG_VERBOSITY_LEVEL"
+
+var _ grpclog.DepthLoggerV2 = (*grpcLogger)(nil)
+
 type grpcLogger struct {
 	l *zerolog.Logger
+
+	verbosity int
+	filter    func(component string, level Level) (Level, bool)
 }
 
+// NewGrpcLogger creates a logger for gRPC. The returned logger implements grpclog.DepthLoggerV2.
+//
+// The verbosity (see grpclog.LoggerV2.V) is read from the GRPC_GO_LOG_VERBOSITY_LEVEL environment variable
+// unless it is set with WithGrpcVerbosity.
 func NewGrpcLogger(level Level, opts ...GrpcOption) grpclog.LoggerV2 {
 	zl := zerolog.New(os.Stdout)
 	zl = zl.Level(zerolog.Level(level))
 
 	l := &grpcLogger{l: &zl}
+	if v, err := strconv.Atoi(os.Getenv(grpcVerbosityEnv)); err == nil {
+		l.verbosity = v
+	}
 	for _, opt := range opts {
 		opt(l)
 	}
@@ -74,6 +94,62 @@ func (l *grpcLogger) Fatalln(args ...any) {
 	l.Fatal(args...)
 }
 
+func (l *grpcLogger) InfoDepth(depth int, args ...any) {
+	l.logDepth(InfoLevel, depth, args)
+}
+
+func (l *grpcLogger) WarningDepth(depth int, args ...any) {
+	l.logDepth(WarnLevel, depth, args)
+}
+
+func (l *grpcLogger) ErrorDepth(depth int, args ...any) {
+	l.logDepth(ErrorLevel, depth, args)
+}
+
+func (l *grpcLogger) FatalDepth(depth int, args ...any) {
+	l.logDepth(FatalLevel, depth, args)
+}
+
+// V reports whether the verbosity level is enabled. gRPC verbosity is not related to the logger level.
 func (l *grpcLogger) V(lvl int) bool {
-	return int(l.l.GetLevel()) <= lvl
+	return lvl <= l.verbosity
+}
+
+// logDepth writes the entry with the caller at the depth relative to the caller of grpclog.*Depth function.
+// The component name prefix ("[transport]") gRPC adds is moved to the field.
+func (l *grpcLogger) logDepth(level Level, depth int, args []any) {
+	var component string
+	if len(args) > 0 {
+		if s, ok := args[0].(string); ok && len(s) > 2 && s[0] == '[' && s[len(s)-1] == ']' {
+			component, args = s[1:len(s)-1], args[1:]
+		}
+	}
+
+	if l.filter != nil && component != "" && level != FatalLevel {
+		var ok bool
+		if level, ok = l.filter(component, level); !ok {
+			return
+		}
+		// Only the Fatal entries of gRPC exit the process.
+		level = min(level, ErrorLevel)
+	}
+
+	var event *zerolog.Event
+	if level == FatalLevel {
+		event = l.l.Fatal()
+	} else {
+		event = l.l.WithLevel(zerolog.Level(level))
+	}
+	if event == nil {
+		return
+	}
+
+	if callerEnabled.Load() {
+		// Skip logDepth, the *Depth method and grpclog.*Depth function.
+		event = event.Caller(depth + 3) //nolint:mnd
+	}
+	if component != "" {
+		event = event.Str(GrpcComponentFieldName, component)
+	}
+	event.Msg(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
 }
@@ -4,9 +4,11 @@ package log_test
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	. "github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/grpclog"
 )
@@ -172,7 +174,7 @@ func TestGrpcLogger_V(t *testing.T) {
 		t.Parallel()
 
 		// Arrange
-		l := NewGrpcLogger(InfoLevel)
+		l := NewGrpcLogger(ErrorLevel, WithGrpcVerbosity(2))
 
 		// Act
 		result := l.V(2)
@@ -185,7 +187,7 @@ func TestGrpcLogger_V(t *testing.T) {
 		t.Parallel()
 
 		// Arrange
-		l := NewGrpcLogger(ErrorLevel)
+		l := NewGrpcLogger(DebugLevel, WithGrpcVerbosity(1))
 
 		// Act
 		result := l.V(2)
@@ -194,3 +196,66 @@ func TestGrpcLogger_V(t *testing.T) {
 		assert.False(t, result)
 	})
 }
+
+func TestGrpcLogger_Depth(t *testing.T) {
+	// Arrange
+	buf := new(bytes.Buffer)
+	grpclog.SetLoggerV2(NewGrpcLogger(DebugLevel,
+		WithGrpcOutput(buf),
+		WithGrpcComponentFilter(func(component string, level Level) (Level, bool) {
+			switch component {
+			case "transport":
+				return level, false
+			case "core":
+				return DebugLevel, true
+			case "server":
+				return FatalLevel, true
+			}
+			return level, true
+		}),
+	))
+	defer grpclog.SetLoggerV2(grpclog.NewLoggerV2(io.Discard, io.Discard, io.Discard))
+
+	t.Run("caller", func(t *testing.T) {
+		// Act
+		SetCallerEnabled(true)
+		grpclog.Component("balancer").Warning("warn message", 42)
+		SetCallerEnabled(false)
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, WarnLevel.String(), entry["level"])
+		assert.Equal(t, "balancer", entry["grpc.component"])
+		assert.Equal(t, "warn message 42", entry[zerolog.MessageFieldName])
+		assert.Contains(t, entry["caller"], "/grpclog_test.go:")
+		buf.Reset()
+	})
+
+	t.Run("dropped component", func(t *testing.T) {
+		// Act
+		grpclog.Component("transport").Info("noise")
+
+		// Assert
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("lowered component", func(t *testing.T) {
+		// Act
+		grpclog.Component("core").Info("info message")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, DebugLevel.String(), entry["level"])
+		buf.Reset()
+	})
+
+	t.Run("raised component", func(t *testing.T) {
+		// Act
+		grpclog.Component("server").Error("error message")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, ErrorLevel.String(), entry["level"])
+		buf.Reset()
+	})
+}
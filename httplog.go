@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Field names injected by AccessLogHandler.
+const (
+	HTTPMethodFieldName     = "http.method"
+	HTTPPathFieldName       = "http.path"
+	HTTPRemoteAddrFieldName = "http.remote_addr"
+	HTTPUserAgentFieldName  = "http.user_agent"
+	HTTPStatusFieldName     = "http.status"
+	HTTPBytesFieldName      = "http.bytes"
+	HTTPDurationFieldName   = "http.duration"
+)
+
+const combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+type httpConfig struct {
+	logger          *Logger
+	requestIDHeader string
+	skip            func(*http.Request) bool
+	statusLevel     func(int) Level
+	combined        io.Writer
+	combinedMu      sync.Mutex
+}
+
+type HTTPOption func(*httpConfig)
+
+// WithHTTPLogger sets the logger stored to the context of the requests.
+// By default, the logger from the request context (see FromContext) is used.
+func WithHTTPLogger(l *Logger) HTTPOption {
+	return func(c *httpConfig) {
+		c.logger = l
+	}
+}
+
+// WithHTTPRequestIDHeader sets the header with the request ID (DefaultRequestIDHeader by default).
+func WithHTTPRequestIDHeader(name string) HTTPOption {
+	return func(c *httpConfig) {
+		c.requestIDHeader = name
+	}
+}
+
+// WithHTTPSkipPaths disables the access log entry for the requests with the specified paths (e.g. "/healthz").
+func WithHTTPSkipPaths(paths ...string) HTTPOption {
+	skipped := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		skipped[p] = struct{}{}
+	}
+	return WithHTTPSkip(func(r *http.Request) bool {
+		_, ok := skipped[r.URL.Path]
+		return ok
+	})
+}
+
+// WithHTTPSkip disables the access log entry for the requests the function returns true for.
+func WithHTTPSkip(f func(*http.Request) bool) HTTPOption {
+	return func(c *httpConfig) {
+		c.skip = f
+	}
+}
+
+// WithHTTPStatusLevel sets the function that selects the level of the access log entry
+// by the response status (DefaultStatusLevel by default).
+func WithHTTPStatusLevel(f func(status int) Level) HTTPOption {
+	return func(c *httpConfig) {
+		c.statusLevel = f
+	}
+}
+
+// WithHTTPCombinedLog writes the access log in the Apache combined format to w
+// instead of the access log entry.
+func WithHTTPCombinedLog(w io.Writer) HTTPOption {
+	return func(c *httpConfig) {
+		c.combined = w
+	}
+}
+
+// DefaultStatusLevel maps the server errors (5xx) to ErrorLevel, the client errors (4xx) to WarnLevel
+// and other statuses to InfoLevel.
+func DefaultStatusLevel(status int) Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return ErrorLevel
+	case status >= http.StatusBadRequest:
+		return WarnLevel
+	default:
+		return InfoLevel
+	}
+}
+
+// AccessLogHandler injects the request fields (method, path, remote address, user agent and request ID)
+// into the request context, stores the logger to the context and writes the access log entry
+// with the response status, the number of bytes written and the duration.
+//
+// The request ID is taken from the request header or generated, and is set to the response header.
+func AccessLogHandler(next http.Handler, opts ...HTTPOption) http.Handler {
+	c := &httpConfig{
+		requestIDHeader: DefaultRequestIDHeader,
+		statusLevel:     DefaultStatusLevel,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(c.requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(c.requestIDHeader, requestID)
+
+		ctx := InjectFields(r.Context(),
+			HTTPMethodFieldName, r.Method,
+			HTTPPathFieldName, r.URL.Path,
+			HTTPRemoteAddrFieldName, r.RemoteAddr,
+			HTTPUserAgentFieldName, r.UserAgent(),
+			RequestIDFieldName, requestID,
+		)
+		if c.logger != nil {
+			ctx = ToContext(ctx, c.logger)
+		} else {
+			ctx = ToContext(ctx, FromContext(ctx))
+		}
+		r = r.WithContext(ctx)
+
+		rw := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		if c.skip != nil && c.skip(r) {
+			return
+		}
+		if c.combined != nil {
+			c.writeCombined(r, rw, start)
+			return
+		}
+		FromContext(ctx).write(ctx, c.statusLevel(rw.Status()), nil, "finished request",
+			HTTPStatusFieldName, rw.Status(),
+			HTTPBytesFieldName, rw.bytes,
+			HTTPDurationFieldName, time.Since(start),
+		)
+	})
+}
+
+func (c *httpConfig) writeCombined(r *http.Request, rw *responseWriter, start time.Time) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		user = u
+	}
+	size := "-"
+	if rw.bytes > 0 {
+		size = strconv.FormatInt(rw.bytes, 10)
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] %q %d %s %q %q\n",
+		host, user, start.Format(combinedTimeFormat),
+		r.Method+" "+r.URL.RequestURI()+" "+r.Proto,
+		rw.Status(), size, headerOrDash(r.Referer()), headerOrDash(r.UserAgent()),
+	)
+
+	c.combinedMu.Lock()
+	defer c.combinedMu.Unlock()
+	_, _ = io.WriteString(c.combined, line)
+}
+
+func headerOrDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+// responseWriter records the status and the number of bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses are not final, except for switching protocols.
+	if w.status == 0 && (status >= http.StatusOK || status == http.StatusSwitchingProtocols) {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijacking is not supported")
+}
+
+// Unwrap allows http.ResponseController to access the original writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
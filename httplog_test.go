@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fields and access entry", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		h := AccessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Info(r.Context(), "handled")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/cache/key?x=1", nil).WithContext(ctx)
+		req.Header.Set("User-Agent", "test-agent")
+		req.Header.Set("X-Request-Id", "req-1")
+		rec := httptest.NewRecorder()
+
+		// Act
+		h.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, "req-1", rec.Header().Get("X-Request-Id"))
+		lines := splitLines(buf.Bytes())
+		require.Len(t, lines, 2)
+
+		handled := decodeEntry(t, lines[0])
+		assert.Equal(t, "GET", handled["http.method"])
+		assert.Equal(t, "/cache/key", handled["http.path"])
+		assert.Equal(t, "test-agent", handled["http.user_agent"])
+		assert.Equal(t, "192.0.2.1:1234", handled["http.remote_addr"])
+		assert.Equal(t, "req-1", handled["request_id"])
+
+		access := decodeEntry(t, lines[1])
+		assert.Equal(t, WarnLevel.String(), access["level"])
+		assert.Equal(t, float64(http.StatusNotFound), access["http.status"])
+		assert.Equal(t, float64(len("not found")), access["http.bytes"])
+		assert.Contains(t, access, "http.duration")
+	})
+
+	t.Run("generated request id", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		h := AccessLogHandler(http.NotFoundHandler())
+		rec := httptest.NewRecorder()
+
+		// Act
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.NotEmpty(t, entry["request_id"])
+		assert.Equal(t, entry["request_id"], rec.Header().Get("X-Request-Id"))
+	})
+
+	t.Run("skip paths", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		ctx, buf := getTestData()
+		h := AccessLogHandler(http.NotFoundHandler(), WithHTTPSkipPaths("/healthz"))
+
+		// Act
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil).WithContext(ctx))
+
+		// Assert
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("combined log", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		combined := &bytes.Buffer{}
+		h := AccessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}), WithHTTPCombinedLog(combined))
+		req := httptest.NewRequest(http.MethodGet, "/a?b=c", nil)
+		req.Header.Set("Referer", "https://example.com/")
+		req.Header.Set("User-Agent", "test-agent")
+		req.SetBasicAuth("frank", "secret")
+
+		// Act
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		// Assert
+		assert.Regexp(t, regexp.MustCompile(
+			`^192\.0\.2\.1 - frank \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /a\?b=c HTTP/1\.1" 200 2 "https://example\.com/" "test-agent"\n$`,
+		), combined.String())
+	})
+}
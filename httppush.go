@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// httpPusher posts the batches of the exporting writers, retrying with the exponential backoff.
+type httpPusher struct {
+	client         *http.Client
+	url            string
+	headers        http.Header
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retryable      func(status int) bool
+}
+
+func newHTTPPusher(url string, retryable func(status int) bool) httpPusher {
+	return httpPusher{
+		client:         &http.Client{Timeout: 10 * time.Second}, //nolint:mnd
+		url:            url,
+		headers:        http.Header{},
+		maxRetries:     5,                      //nolint:mnd
+		initialBackoff: 500 * time.Millisecond, //nolint:mnd
+		maxBackoff:     30 * time.Second,       //nolint:mnd
+		retryable:      retryable,
+	}
+}
+
+// push posts the body until it's accepted, the response isn't retryable, the retries are exhausted
+// or the context is done.
+//
+// The Retry-After header of the response overrides the backoff.
+func (p *httpPusher) push(ctx context.Context, body []byte, header http.Header) error {
+	backoff := p.initialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, delay, err := p.send(ctx, body, header)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= p.maxRetries {
+			return err
+		}
+
+		if delay == 0 {
+			delay = backoff
+			backoff = min(backoff*2, p.maxBackoff) //nolint:mnd
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// send makes one request and reports whether it may be retried and after what delay.
+func (p *httpPusher) send(ctx context.Context, body []byte, header http.Header) (bool, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	for key, values := range p.headers {
+		req.Header[key] = values
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, 0, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+
+	err = fmt.Errorf("server responded with %s", resp.Status)
+	if !p.retryable(resp.StatusCode) {
+		return false, 0, err
+	}
+	var delay time.Duration
+	if seconds, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && seconds > 0 {
+		delay = time.Duration(seconds) * time.Second
+	}
+	return true, delay, err
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
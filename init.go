@@ -14,5 +14,13 @@ func init() {
 }
 
 func Init(level Level, opts ...Option) {
-	zerolog.DefaultContextLogger = NewLogger(level, opts...).l
+	l := NewLogger(level, opts...)
+
+	globalLevel.mu.Lock()
+	defer globalLevel.mu.Unlock()
+
+	globalLevel.cancelRevert()
+	defaultLogger.Store(l.l)
+	// For the callers of zerolog.Ctx, the level changes are seen by FromContext only.
+	zerolog.DefaultContextLogger = l.l
 }
@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/unix"
+)
+
+// DefaultJournaldSocket is the socket of the journald native protocol.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+const maxJournaldFieldName = 64
+
+// JournaldWriter writes the entries to systemd-journald with the native protocol.
+//
+// The level is mapped to PRIORITY, the message to MESSAGE, the caller to CODE_FILE and CODE_LINE,
+// and the rest of the fields are sent as the upper-cased journal fields (e.g. "request_id" as REQUEST_ID).
+// The entries too large for a datagram are passed with a sealed memfd.
+type JournaldWriter struct {
+	identifier string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+type journaldConfig struct {
+	socket     string
+	identifier string
+}
+
+type JournaldOption func(*journaldConfig)
+
+// WithJournaldSocket sets the path of the journald socket (DefaultJournaldSocket by default).
+func WithJournaldSocket(path string) JournaldOption {
+	return func(c *journaldConfig) {
+		c.socket = path
+	}
+}
+
+// WithJournaldIdentifier sets SYSLOG_IDENTIFIER (the executable name by default).
+func WithJournaldIdentifier(identifier string) JournaldOption {
+	return func(c *journaldConfig) {
+		c.identifier = identifier
+	}
+}
+
+// NewJournaldWriter creates a writer to the journald socket.
+func NewJournaldWriter(opts ...JournaldOption) (*JournaldWriter, error) {
+	c := &journaldConfig{
+		socket:     DefaultJournaldSocket,
+		identifier: filepath.Base(os.Args[0]),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldWriter{
+		identifier: c.identifier,
+		conn:       conn,
+		addr:       &net.UnixAddr{Name: c.socket, Net: "unixgram"},
+	}, nil
+}
+
+// Write writes the entry with the priority mapped from its "level" field.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel writes the entry with the priority mapped from the level.
+func (w *JournaldWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	data := w.format(level, p)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, _, err := w.conn.WriteMsgUnix(data, nil, w.addr)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		err = w.sendMemfd(data)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the socket.
+func (w *JournaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// sendMemfd passes the entry with the sealed memfd, as sd_journal_sendv does for the large entries.
+func (w *JournaldWriter) sendMemfd(data []byte) error {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	for written := 0; written < len(data); {
+		n, err := unix.Write(fd, data[written:])
+		if err != nil {
+			return err
+		}
+		written += n
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS,
+		unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return err
+	}
+
+	_, _, err = w.conn.WriteMsgUnix(nil, unix.UnixRights(fd), w.addr)
+	return err
+}
+
+func (w *JournaldWriter) format(level zerolog.Level, p []byte) []byte {
+	var buf bytes.Buffer
+	if w.identifier != "" {
+		appendJournaldField(&buf, "SYSLOG_IDENTIFIER", w.identifier)
+	}
+
+	fields, err := parseJSONFields(p)
+	if err != nil {
+		appendJournaldField(&buf, "PRIORITY", strconv.Itoa(int(levelToSyslogPriority(Level(level)))))
+		appendJournaldField(&buf, "MESSAGE", strings.TrimRight(string(p), "\n"))
+		return buf.Bytes()
+	}
+
+	for _, f := range fields {
+		value, err := logfmtValue(f.raw)
+		if err != nil {
+			value = string(f.raw)
+		}
+
+		switch f.key {
+		case zerolog.LevelFieldName:
+			if level == zerolog.NoLevel {
+				_, level = levelText(f.raw)
+			}
+		case zerolog.TimestampFieldName:
+			// The journal has its own timestamps.
+		case zerolog.MessageFieldName:
+			appendJournaldField(&buf, "MESSAGE", value)
+		case zerolog.CallerFieldName:
+			file, line := value, ""
+			if i := strings.LastIndexByte(value, ':'); i >= 0 {
+				file, line = value[:i], value[i+1:]
+			}
+			appendJournaldField(&buf, "CODE_FILE", file)
+			if line != "" {
+				appendJournaldField(&buf, "CODE_LINE", line)
+			}
+		default:
+			appendJournaldField(&buf, journaldFieldName(f.key), value)
+		}
+	}
+	appendJournaldField(&buf, "PRIORITY", strconv.Itoa(int(levelToSyslogPriority(Level(level)))))
+
+	return buf.Bytes()
+}
+
+// appendJournaldField appends "NAME=value\n", or the binary-safe "NAME\n<64-bit LE length>value\n"
+// if the value has newlines.
+func appendJournaldField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.ContainsRune(value, '\n') {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journaldFieldName returns the valid journal field name: up to 64 upper-case letters, digits and underscores,
+// not starting with an underscore or a digit.
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "FIELD_" + name
+	}
+	if len(name) > maxJournaldFieldName {
+		name = name[:maxJournaldFieldName]
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: MIT
+
+//go:build !linux
+
+package log
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultJournaldSocket is the socket of the journald native protocol.
+const DefaultJournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldWriter writes the entries to systemd-journald with the native protocol.
+// It's supported only on Linux.
+type JournaldWriter struct{}
+
+type journaldConfig struct{}
+
+type JournaldOption func(*journaldConfig)
+
+// WithJournaldSocket sets the path of the journald socket (DefaultJournaldSocket by default).
+func WithJournaldSocket(string) JournaldOption {
+	return func(*journaldConfig) {}
+}
+
+// WithJournaldIdentifier sets SYSLOG_IDENTIFIER (the executable name by default).
+func WithJournaldIdentifier(string) JournaldOption {
+	return func(*journaldConfig) {}
+}
+
+// NewJournaldWriter returns an error, since journald is supported only on Linux.
+func NewJournaldWriter(...JournaldOption) (*JournaldWriter, error) {
+	return nil, errors.New("journald is supported only on linux")
+}
+
+func (w *JournaldWriter) Write([]byte) (int, error) {
+	return 0, errors.New("journald is supported only on linux")
+}
+
+func (w *JournaldWriter) WriteLevel(zerolog.Level, []byte) (int, error) {
+	return 0, errors.New("journald is supported only on linux")
+}
+
+func (w *JournaldWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func listenJournald(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, path
+}
+
+// readJournald reads the entry from the datagram or from the passed memfd.
+func readJournald(t *testing.T, conn *net.UnixConn) map[string][]string {
+	t.Helper()
+
+	buf := make([]byte, 1024*1024)
+	oob := make([]byte, unix.CmsgSpace(4))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	require.NoError(t, err)
+	data := buf[:n]
+
+	if oobn > 0 {
+		msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+		require.NoError(t, err)
+		fds, err := unix.ParseUnixRights(&msgs[0])
+		require.NoError(t, err)
+		f := os.NewFile(uintptr(fds[0]), "memfd")
+		defer f.Close()
+		_, err = f.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		var content bytes.Buffer
+		_, err = content.ReadFrom(f)
+		require.NoError(t, err)
+		data = content.Bytes()
+	}
+
+	return parseJournald(t, data)
+}
+
+func parseJournald(t *testing.T, data []byte) map[string][]string {
+	t.Helper()
+
+	fields := map[string][]string{}
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		require.GreaterOrEqual(t, i, 0)
+		name := string(data[:i])
+
+		if data[i] == '=' {
+			end := bytes.IndexByte(data[i:], '\n')
+			fields[name] = append(fields[name], string(data[i+1:i+end]))
+			data = data[i+end+1:]
+			continue
+		}
+
+		size := binary.LittleEndian.Uint64(data[i+1 : i+9])
+		value := data[i+9 : i+9+int(size)]
+		fields[name] = append(fields[name], string(value))
+		data = data[i+9+int(size)+1:]
+	}
+	return fields
+}
+
+func TestJournaldWriter(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	conn, path := listenJournald(t)
+	w, err := NewJournaldWriter(WithJournaldSocket(path), WithJournaldIdentifier("edge"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutput(w))
+
+	// Act
+	l.Error(context.Background(), errors.New("boom"), "purge failed",
+		"request_id", "r1", "http.path", "/img", "stack", "line1\nline2")
+
+	// Assert
+	fields := readJournald(t, conn)
+	assert.Equal(t, []string{"edge"}, fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, []string{"3"}, fields["PRIORITY"])
+	assert.Equal(t, []string{"purge failed"}, fields["MESSAGE"])
+	assert.Equal(t, []string{"boom"}, fields["ERROR"])
+	assert.Equal(t, []string{"r1"}, fields["REQUEST_ID"])
+	assert.Equal(t, []string{"/img"}, fields["HTTP_PATH"])
+	assert.Equal(t, []string{"line1\nline2"}, fields["STACK"])
+	assert.NotContains(t, fields, "TIME")
+	assert.NotContains(t, fields, "LEVEL")
+}
+
+func TestJournaldWriter_Caller(t *testing.T) {
+	SetCallerEnabled(true)
+	t.Cleanup(func() { SetCallerEnabled(false) })
+
+	// Arrange
+	conn, path := listenJournald(t)
+	w, err := NewJournaldWriter(WithJournaldSocket(path))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	ctx := ToContext(context.Background(), NewLogger(DebugLevel, WithOutput(w)))
+
+	// Act
+	Debug(ctx, "detail")
+
+	// Assert
+	fields := readJournald(t, conn)
+	assert.Equal(t, []string{"7"}, fields["PRIORITY"])
+	require.Len(t, fields["CODE_FILE"], 1)
+	assert.True(t, strings.HasSuffix(fields["CODE_FILE"][0], "journald_test.go"), fields["CODE_FILE"])
+	assert.NotEmpty(t, fields["CODE_LINE"])
+}
+
+func TestJournaldWriter_Memfd(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	conn, path := listenJournald(t)
+	w, err := NewJournaldWriter(WithJournaldSocket(path))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithOutput(w))
+	large := strings.Repeat("x", 512*1024)
+
+	// Act
+	l.Info(context.Background(), "large", "payload", large)
+
+	// Assert
+	fields := readJournald(t, conn)
+	assert.Equal(t, []string{"large"}, fields["MESSAGE"])
+	assert.Equal(t, []string{large}, fields["PAYLOAD"])
+}
+
+func TestJournaldWriter_FieldNames(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	conn, path := listenJournald(t)
+	w, err := NewJournaldWriter(WithJournaldSocket(path))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "names", "_private", 1, "1st", 2, "grpc-code", "OK")
+
+	// Assert
+	fields := readJournald(t, conn)
+	assert.Equal(t, []string{"1"}, fields["PRIVATE"])
+	assert.Equal(t, []string{"2"}, fields["FIELD_1ST"])
+	assert.Equal(t, []string{"OK"}, fields["GRPC_CODE"])
+}
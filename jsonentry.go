@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+var errNotJSONObject = errors.New("not a JSON object")
+
+// jsonField is the field of the JSON entry with the raw value.
+type jsonField struct {
+	key string
+	raw json.RawMessage
+}
+
+// parseJSONFields returns the fields of the JSON entry in the order of appearance.
+func parseJSONFields(p []byte) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+
+	if t, err := dec.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, errNotJSONObject
+	}
+
+	var fields []jsonField
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := t.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		fields = append(fields, jsonField{key: key, raw: raw})
+	}
+	return fields, nil
+}
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const maxLevelRequestSize = 1024
+
+// globalLevel serializes the changes of the default logger level.
+var globalLevel levelControl
+
+type levelControl struct {
+	mu sync.Mutex
+
+	revert      *time.Timer
+	revertLevel Level
+}
+
+// GetGlobalLevel returns the level of the default logger.
+func GetGlobalLevel() Level {
+	globalLevel.mu.Lock()
+	defer globalLevel.mu.Unlock()
+
+	return defaultLoggerLevel()
+}
+
+// SetGlobalLevelFor sets the level of the default logger and reverts it after ttl.
+// If ttl is not positive, then the level is not reverted.
+//
+// If the revert of an earlier change is pending, then the level is reverted
+// to the one that was set before that change.
+func SetGlobalLevelFor(level Level, ttl time.Duration) {
+	globalLevel.mu.Lock()
+	defer globalLevel.mu.Unlock()
+
+	globalLevel.set(level, ttl, "")
+}
+
+// set must be called with the lock held. The change is logged if the source is specified.
+func (c *levelControl) set(level Level, ttl time.Duration, source string) {
+	prev := defaultLoggerLevel()
+	if c.revert != nil {
+		prev = c.revertLevel
+	}
+	c.cancelRevert()
+
+	setDefaultLoggerLevel(level)
+	if source != "" {
+		logLevelChange(level, source)
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(ttl, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.revert != t {
+			// Cancelled or replaced.
+			return
+		}
+		c.revert = nil
+		setDefaultLoggerLevel(c.revertLevel)
+		logLevelChange(c.revertLevel, "ttl")
+	})
+	c.revert = t
+	c.revertLevel = prev
+}
+
+// cancelRevert must be called with the lock held.
+func (c *levelControl) cancelRevert() {
+	if c.revert != nil {
+		c.revert.Stop()
+		c.revert = nil
+	}
+}
+
+func defaultLoggerLevel() Level {
+	zl := defaultLogger.Load()
+	if zl == nil {
+		return Level(zerolog.Disabled)
+	}
+	return Level(zl.GetLevel())
+}
+
+// setDefaultLoggerLevel must be called with the lock held, the readers (FromContext) see either logger.
+func setDefaultLoggerLevel(level Level) {
+	zl := defaultLogger.Load()
+	if zl == nil {
+		defaultLogger.Store(NewLogger(level).l)
+		return
+	}
+	l := zl.Level(zerolog.Level(level))
+	defaultLogger.Store(&l)
+}
+
+func logLevelChange(level Level, source string) {
+	FromContext(context.Background()).Force(context.Background(), "log level changed",
+		"level", level.String(),
+		"source", source,
+	)
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+type levelResponse struct {
+	Level string `json:"level"`
+}
+
+// NewLevelHandler returns an HTTP handler that reports (GET) and changes (PUT)
+// the level of the default logger.
+//
+// The level is accepted as a plain text body ("debug") or a JSON object ({"level":"debug"}),
+// depending on the request Content-Type, and parsed with ParseLevel.
+// Optional TTL ("ttl" query parameter or JSON field in time.ParseDuration format)
+// reverts the level after the specified period, see SetGlobalLevelFor.
+//
+// The response is JSON if the request is JSON or accepts "application/json", and plain text otherwise.
+func NewLevelHandler() http.Handler {
+	return http.HandlerFunc(serveLevel)
+}
+
+func serveLevel(w http.ResponseWriter, r *http.Request) {
+	isJSON := hasMediaType(r.Header.Get("Content-Type"), "application/json")
+	respondJSON := isJSON || strings.Contains(r.Header.Get("Accept"), "application/json")
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		req, err := readLevelRequest(r, isJSON)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		level, err := ParseLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			if ttl, err = time.ParseDuration(req.TTL); err != nil {
+				http.Error(w, fmt.Sprintf("cannot parse ttl %q: %s", req.TTL, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		globalLevel.mu.Lock()
+		globalLevel.set(level, ttl, "http")
+		globalLevel.mu.Unlock()
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	level := GetGlobalLevel().String()
+	if respondJSON {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levelResponse{Level: level})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintln(w, level)
+}
+
+func readLevelRequest(r *http.Request, isJSON bool) (levelRequest, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxLevelRequestSize))
+	if err != nil {
+		return levelRequest{}, fmt.Errorf("cannot read request: %w", err)
+	}
+
+	req := levelRequest{TTL: r.URL.Query().Get("ttl")}
+	if !isJSON {
+		req.Level = strings.TrimSpace(string(body))
+		return req, nil
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return levelRequest{}, fmt.Errorf("cannot parse request: %w", err)
+	}
+	return req, nil
+}
+
+func hasMediaType(contentType, want string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == want
+}
+
+// HandleLevelSignals steps the level of the default logger on signals until the context is done:
+// SIGUSR1 makes the logger more verbose (e.g. INFO to DEBUG) and SIGUSR2 makes it less verbose.
+//
+// If ttl is positive, then the level is reverted after ttl since the last signal.
+func HandleLevelSignals(ctx context.Context, ttl time.Duration) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				step := Level(1)
+				if sig == syscall.SIGUSR1 {
+					step = -1
+				}
+				stepGlobalLevel(step, ttl)
+			}
+		}
+	}()
+}
+
+func stepGlobalLevel(step Level, ttl time.Duration) {
+	globalLevel.mu.Lock()
+	defer globalLevel.mu.Unlock()
+
+	level := min(max(defaultLoggerLevel()+step, minAllowedLevel), maxAllowedLevel)
+	globalLevel.set(level, ttl, "signal")
+}
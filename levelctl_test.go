@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveLevel(t *testing.T, method, contentType, target, body string) (*http.Response, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	NewLevelHandler().ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(data)
+}
+
+func TestLevelHandler(t *testing.T) {
+	Init(InfoLevel, WithOutput(io.Discard))
+	defer Init(InfoLevel)
+
+	t.Run("get", func(t *testing.T) {
+		// Act
+		resp, body := serveLevel(t, http.MethodGet, "", "/", "")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "INFO\n", body)
+	})
+
+	t.Run("put plain text", func(t *testing.T) {
+		// Act
+		resp, body := serveLevel(t, http.MethodPut, "text/plain", "/", "debug\n")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "DEBUG\n", body)
+		assert.Equal(t, DebugLevel, GetGlobalLevel())
+	})
+
+	t.Run("put json", func(t *testing.T) {
+		// Act
+		resp, body := serveLevel(t, http.MethodPut, "application/json", "/", `{"level":"warn"}`)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.JSONEq(t, `{"level":"WARN"}`, body)
+		assert.Equal(t, WarnLevel, GetGlobalLevel())
+	})
+
+	t.Run("put with ttl", func(t *testing.T) {
+		// Arrange
+		SetGlobalLevel(InfoLevel)
+
+		// Act
+		resp, _ := serveLevel(t, http.MethodPut, "", "/?ttl=20ms", "trace")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, TraceLevel, GetGlobalLevel())
+		assert.Eventually(t, func() bool {
+			return GetGlobalLevel() == InfoLevel
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		// Act
+		resp, _ := serveLevel(t, http.MethodPut, "", "/", "verbose")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		// Act
+		resp, _ := serveLevel(t, http.MethodPost, "", "/", "debug")
+
+		// Assert
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestLevelHandler_ConcurrentLogging(t *testing.T) {
+	// Arrange
+	Init(InfoLevel, WithOutput(io.Discard))
+	defer Init(InfoLevel)
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			level := []string{"debug", "warn"}[i%2]
+			resp, _ := serveLevel(t, http.MethodPut, "", "/", level)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+	}()
+	for range 100 {
+		Info(context.Background(), "logged")
+	}
+	<-done
+
+	// Assert
+	assert.Equal(t, WarnLevel, GetGlobalLevel())
+}
+
+func TestInit_DefaultContextLogger(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	Init(InfoLevel, WithOutput(&buf))
+	defer Init(InfoLevel)
+
+	// Act
+	zerolog.Ctx(context.Background()).Info().Msg("zerolog")
+	SetGlobalLevel(DebugLevel)
+	Debug(context.Background(), "debugged")
+
+	// Assert
+	assert.Contains(t, buf.String(), `"message":"zerolog"`)
+	assert.Contains(t, buf.String(), `"message":"debugged"`)
+}
+
+func TestSetGlobalLevelFor(t *testing.T) {
+	Init(InfoLevel, WithOutput(io.Discard))
+	defer Init(InfoLevel)
+
+	// Act
+	SetGlobalLevelFor(DebugLevel, 20*time.Millisecond)
+	SetGlobalLevelFor(TraceLevel, 20*time.Millisecond)
+
+	// Assert
+	assert.Equal(t, TraceLevel, GetGlobalLevel())
+	assert.Eventually(t, func() bool {
+		return GetGlobalLevel() == InfoLevel
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleLevelSignals(t *testing.T) {
+	Init(InfoLevel, WithOutput(io.Discard))
+	defer Init(InfoLevel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	HandleLevelSignals(ctx, 0)
+
+	// Act
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		return GetGlobalLevel() == DebugLevel
+	}, time.Second, 5*time.Millisecond)
+
+	for _, want := range []Level{InfoLevel, WarnLevel} {
+		// Act
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+
+		// Assert
+		assert.Eventually(t, func() bool {
+			return GetGlobalLevel() == want
+		}, time.Second, 5*time.Millisecond)
+	}
+}
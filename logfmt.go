@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// LogfmtWriter converts the JSON entries to the logfmt format (`level=INFO msg="cache miss" key=value`).
+//
+// The time, the level, the caller and the message (with the conventional "msg" key) go first, followed by
+// the rest of the fields in the order they were added. The nested values (objects and arrays) are written as quoted JSON.
+type LogfmtWriter struct {
+	out io.Writer
+}
+
+// NewLogfmtWriter creates a writer that writes the entries in the logfmt format to w.
+func NewLogfmtWriter(w io.Writer) *LogfmtWriter {
+	return &LogfmtWriter{out: w}
+}
+
+const logfmtMessageKey = "msg"
+
+type logfmtField struct {
+	key   string
+	value string
+}
+
+func (w *LogfmtWriter) Write(p []byte) (int, error) {
+	line, err := formatLogfmt(p)
+	if err != nil {
+		// Not a JSON entry, write it as is.
+		return w.out.Write(p)
+	}
+	if _, err := w.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteLevel passes the level to the inner writer if it implements zerolog.LevelWriter.
+func (w *LogfmtWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	line, err := formatLogfmt(p)
+	if err != nil {
+		return writeLevel(w.out, level, p)
+	}
+	if _, err := writeLevel(w.out, level, line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func formatLogfmt(p []byte) ([]byte, error) {
+	entry, err := parseJSONFields(p)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]logfmtField, 0, len(entry))
+	for _, f := range entry {
+		value, err := logfmtValue(f.raw)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, logfmtField{key: f.key, value: value})
+	}
+
+	var buf bytes.Buffer
+	for _, key := range []string{
+		zerolog.TimestampFieldName,
+		zerolog.LevelFieldName,
+		zerolog.CallerFieldName,
+		zerolog.MessageFieldName,
+	} {
+		for i, f := range fields {
+			if f.key == key {
+				if key == zerolog.MessageFieldName {
+					f.key = logfmtMessageKey
+				}
+				appendLogfmtField(&buf, f)
+				fields = append(fields[:i], fields[i+1:]...)
+				break
+			}
+		}
+	}
+	for _, f := range fields {
+		appendLogfmtField(&buf, f)
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// logfmtValue returns the unquoted string value or the compacted nested value.
+func logfmtValue(raw json.RawMessage) (string, error) {
+	switch raw[0] {
+	case '"':
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	case '{', '[':
+		var compact bytes.Buffer
+		err := json.Compact(&compact, raw)
+		return compact.String(), err
+	default:
+		return string(raw), nil
+	}
+}
+
+func appendLogfmtField(buf *bytes.Buffer, f logfmtField) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(logfmtKey(f.key))
+	buf.WriteByte('=')
+	if needsQuoting(f.value) {
+		buf.WriteString(strconv.Quote(f.value))
+	} else {
+		buf.WriteString(f.value)
+	}
+}
+
+// logfmtKey replaces the characters not allowed in the keys with underscores.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
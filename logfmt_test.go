@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogfmt(t *testing.T) {
+	t.Parallel()
+
+	t.Run("quoting", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		buf := &bytes.Buffer{}
+		l := NewLogger(DebugLevel, WithLogfmt(buf))
+
+		// Act
+		l.Info(context.Background(), "cache miss",
+			"key", "/img/logo.png",
+			"spaced", "a b",
+			"quoted", `say "hi"`,
+			"multiline", "line1\nline2",
+			"empty", "",
+			"eq", "a=b",
+			"count", 3,
+			"hit", false,
+		)
+
+		// Assert
+		assert.Equal(t,
+			`level=INFO msg="cache miss" key=/img/logo.png spaced="a b" quoted="say \"hi\"" `+
+				`multiline="line1\nline2" empty="" eq="a=b" count=3 hit=false`+"\n",
+			buf.String())
+	})
+
+	t.Run("order", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		buf := &bytes.Buffer{}
+		l := NewLogger(DebugLevel, WithTimestamp(), WithLogfmt(buf))
+
+		// Act
+		l.Error(context.Background(), errors.New("boom"), "failed", "node", "edge-1", "attempt", 2)
+
+		// Assert
+		line := buf.String()
+		require.True(t, strings.HasSuffix(line, "\n"))
+		keys := logfmtKeys(line)
+		assert.Equal(t, []string{zerolog.TimestampFieldName, "level", "msg", "error", "node", "attempt"}, keys)
+		assert.Contains(t, line, " level=ERROR msg=failed error=boom node=edge-1 attempt=2\n")
+	})
+
+	t.Run("nested values", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		buf := &bytes.Buffer{}
+		l := NewLogger(DebugLevel, WithLogfmt(buf))
+
+		// Act
+		l.Info(context.Background(), "nested",
+			"tags", []string{"a", "b c"},
+			"meta", map[string]any{"k": "v"},
+		)
+
+		// Assert
+		assert.Equal(t, `level=INFO msg=nested tags="[\"a\",\"b c\"]" meta="{\"k\":\"v\"}"`+"\n", buf.String())
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		buf := &bytes.Buffer{}
+		l := NewLogger(DebugLevel, WithLogfmt(buf))
+
+		// Act
+		l.Info(context.Background(), "keys", "a key", 1, "a=b", 2)
+
+		// Assert
+		assert.Equal(t, "level=INFO msg=keys a_key=1 a_b=2\n", buf.String())
+	})
+}
+
+func TestLogfmtWriter_NotJSON(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	buf := &bytes.Buffer{}
+	w := NewLogfmtWriter(buf)
+
+	// Act
+	n, err := w.Write([]byte("plain text\n"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 11, n)
+	assert.Equal(t, "plain text\n", buf.String())
+}
+
+func TestWithGrpcLogfmt(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	buf := &bytes.Buffer{}
+	l := NewGrpcLogger(InfoLevel, WithGrpcLogfmt(buf))
+
+	// Act
+	l.Warning("connection reset")
+
+	// Assert
+	assert.Equal(t, "level=WARN msg=\"connection reset\"\n", buf.String())
+}
+
+func logfmtKeys(line string) []string {
+	var keys []string
+	for _, part := range strings.Fields(line) {
+		if k, _, ok := strings.Cut(part, "="); ok && !strings.ContainsAny(k, `"`) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
@@ -5,6 +5,7 @@ package log
 import (
 	"context"
 	"os"
+	"slices"
 	"sync/atomic"
 
 	"github.com/rs/zerolog"
@@ -17,12 +18,42 @@ var (
 
 type Logger struct {
 	l *zerolog.Logger
+
+	// fields are the fields bound with With when deduplication is enabled.
+	// Otherwise, the fields are stored in the zerolog context.
+	fields Fields
+	// group is the prefix for the keys of the fields bound with With and passed per call.
+	group string
+	// name is the name set with Named.
+	name string
+	// followsDefault is set for the loggers created with the package-level Named,
+	// which write with the current default logger instead of l (see Init).
+	followsDefault bool
+	// redactor removes the sensitive data, see WithRedaction.
+	redactor *Redactor
+	// sampler limits the repeated entries, see WithSampling.
+	sampler *sampler
 }
 
+type loggerKey struct{}
+
+// defaultLogger is the logger returned by FromContext for the contexts without a logger.
+// It's replaced atomically, since the level can be changed while logging (see NewLevelHandler).
+var defaultLogger atomic.Pointer[zerolog.Logger]
+
+// disabledLogger is the logger zerolog.Ctx returns for the contexts without a zerolog logger
+// until Init sets zerolog.DefaultContextLogger.
+var disabledLogger = zerolog.Ctx(context.Background())
+
 // SetGlobalLevel creates a logger with specified level and stores it as default logger.
+//
+// Cancels the pending revert of the level set with SetGlobalLevelFor.
 func SetGlobalLevel(level Level) {
-	l := zerolog.DefaultContextLogger.Level(zerolog.Level(level))
-	zerolog.DefaultContextLogger = &l
+	globalLevel.mu.Lock()
+	defer globalLevel.mu.Unlock()
+
+	globalLevel.cancelRevert()
+	setDefaultLoggerLevel(level)
 }
 
 // SetCallerEnabled sets the global flag that determines whether to add in information
@@ -52,72 +83,221 @@ func NewLogger(level Level, opts ...Option) *Logger {
 }
 
 func ToContext(ctx context.Context, logger *Logger) context.Context {
-	return logger.l.WithContext(ctx)
+	return context.WithValue(logger.base().WithContext(ctx), loggerKey{}, logger)
 }
 
+// FromContext returns the logger stored in the context with ToContext or the default logger.
+//
+// If the level is overridden with WithContextLevel, then the returned logger has that level.
 func FromContext(ctx context.Context) *Logger {
-	return &Logger{l: zerolog.Ctx(ctx)}
+	l, ok := ctx.Value(loggerKey{}).(*Logger)
+	if !ok {
+		l = &Logger{l: contextZerolog(ctx)}
+	}
+	if level, ok := ContextLevel(ctx); ok && level < l.GetLevel() {
+		return l.Level(level)
+	}
+	return l
+}
+
+// contextZerolog returns the zerolog logger attached to the context, the default logger or the disabled logger.
+func contextZerolog(ctx context.Context) *zerolog.Logger {
+	// zerolog.Ctx returns zerolog.DefaultContextLogger (set by Init) or the disabled logger
+	// for the contexts without a logger, the default logger is used instead then.
+	if zl := zerolog.Ctx(ctx); zl != disabledLogger && zl != zerolog.DefaultContextLogger {
+		return zl
+	}
+	if zl := defaultLogger.Load(); zl != nil {
+		return zl
+	}
+	return disabledLogger
 }
 
 func (l *Logger) GetLevel() Level {
-	return Level(l.l.GetLevel())
+	return Level(l.base().GetLevel())
 }
 
 func (l *Logger) Level(level Level) *Logger {
-	zl := l.l.Level(zerolog.Level(level))
-	return &Logger{l: &zl}
+	return l.withZerolog(l.base().Level(zerolog.Level(level)))
+}
+
+// With creates a child logger that adds the specified fields to every entry.
+//
+// If deduplication is enabled (see SetDeduplicationEnabled), then the fields injected
+// to the context override the bound fields with the same keys, and the fields passed
+// per call override both of them.
+func (l *Logger) With(fields ...any) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+
+	fields = l.groupFields(fields)
+	if deduplicationEnabled.Load() {
+		c := *l
+		c.fields = l.fields.With(fields)
+		return &c
+	}
+	return l.withZerolog(l.base().With().Fields([]any(l.redactor.Fields(fields))).Logger())
+}
+
+// WithGroup creates a child logger that prefixes the keys of the fields bound with With
+// and passed per call with the group name ("group.key"). Nested groups are joined with a dot.
+//
+// The fields injected to the context are not affected.
+func (l *Logger) WithGroup(name string) *Logger {
+	if name == "" {
+		return l
+	}
+
+	c := *l
+	if c.group == "" {
+		c.group = name
+	} else {
+		c.group += "." + name
+	}
+	return &c
+}
+
+// zerolog returns the zerolog logger with the level overridden with WithContextLevel
+// or with the level spec for the named loggers, if any.
+func (l *Logger) zerolog(ctx context.Context) *zerolog.Logger {
+	if level := l.levelFor(ctx); level != l.GetLevel() {
+		zl := l.base().Level(zerolog.Level(level))
+		return &zl
+	}
+	return l.base()
+}
+
+// base returns the zerolog logger to write with: the current default logger for the loggers
+// created with the package-level Named, l otherwise.
+func (l *Logger) base() *zerolog.Logger {
+	if l.followsDefault {
+		return contextZerolog(context.Background())
+	}
+	return l.l
+}
+
+// levelFor returns the level of the logger (see SetLevelSpec), lowered by the context override.
+func (l *Logger) levelFor(ctx context.Context) Level {
+	level := l.GetLevel()
+	if l.name != "" {
+		if named, ok := namedLevel(l.name); ok {
+			level = named
+		}
+	}
+	if override, ok := ContextLevel(ctx); ok && override < level {
+		return override
+	}
+	return level
+}
+
+func (l *Logger) withZerolog(zl zerolog.Logger) *Logger {
+	c := *l
+	c.l = &zl
+	c.followsDefault = false
+	return &c
+}
+
+func (l *Logger) groupFields(fields Fields) Fields {
+	if l.group == "" || len(fields) == 0 {
+		return fields
+	}
+
+	result := make(Fields, 0, len(fields)+len(fields)%2)
+	i := fields.Iterator()
+	for k, v, ok := i.Next(); ok; k, v, ok = i.Next() {
+		result = append(result, l.group+"."+k, v)
+	}
+	return result
 }
 
 func (l *Logger) DebugWithTrace(ctx context.Context, msg, trace string, fields ...any) {
-	event := l.l.Debug()
-	event = withFieldsAndCaller(ctx, event, fields)
-	if l.GetLevel() == TraceLevel {
+	if l.buffered(ctx, DebugLevel, msg, slices.Concat(fields, Fields{"trace", trace})) || !l.sampled(ctx, DebugLevel, msg) {
+		return
+	}
+	event := l.zerolog(ctx).Debug()
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	if l.levelFor(ctx) == TraceLevel {
 		event = event.Str("trace", trace)
 	}
-	event.Msg(msg)
+	event.Msg(l.redactor.String(msg))
 }
 
 func (l *Logger) Debug(ctx context.Context, msg string, fields ...any) {
-	event := l.l.Debug()
-	event = withFieldsAndCaller(ctx, event, fields)
-	event.Msg(msg)
+	if l.buffered(ctx, DebugLevel, msg, fields) || !l.sampled(ctx, DebugLevel, msg) {
+		return
+	}
+	event := l.zerolog(ctx).Debug()
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
 }
 
 func (l *Logger) Info(ctx context.Context, msg string, fields ...any) {
-	event := l.l.Info()
-	event = withFieldsAndCaller(ctx, event, fields)
-	event.Msg(msg)
+	if l.buffered(ctx, InfoLevel, msg, fields) || !l.sampled(ctx, InfoLevel, msg) {
+		return
+	}
+	event := l.zerolog(ctx).Info()
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
 }
 
 func (l *Logger) Warn(ctx context.Context, msg string, fields ...any) {
-	event := l.l.Warn()
-	event = withFieldsAndCaller(ctx, event, fields)
-	event.Msg(msg)
+	if l.buffered(ctx, WarnLevel, msg, fields) || !l.sampled(ctx, WarnLevel, msg) {
+		return
+	}
+	event := l.zerolog(ctx).Warn()
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
 }
 
 func (l *Logger) Error(ctx context.Context, err error, msg string, fields ...any) {
-	event := l.l.Error().Err(err)
-	event = withFieldsAndCaller(ctx, event, fields)
-	event.Msg(msg)
+	Dump(ctx)
+	if !l.sampled(ctx, ErrorLevel, msg) {
+		return
+	}
+	event := l.withError(l.zerolog(ctx).Error(), ErrorLevel, err)
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
 }
 
 func (l *Logger) Fatal(ctx context.Context, msg string, fields ...any) {
-	event := l.l.Fatal()
-	event = withFieldsAndCaller(ctx, event, fields)
-	event.Msg(msg)
+	event := l.zerolog(ctx).Fatal()
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
 }
 
 func (l *Logger) FatalError(ctx context.Context, err error, msg string, fields ...any) {
-	event := l.l.Fatal().Err(err)
-	event = withFieldsAndCaller(ctx, event, fields)
-	event.Msg(msg)
+	Dump(ctx)
+	event := l.withError(l.zerolog(ctx).Fatal(), FatalLevel, err)
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
 }
 
 func (l *Logger) Force(ctx context.Context, msg string, fields ...any) {
-	l2 := l.l.Level(zerolog.InfoLevel)
+	l2 := l.base().Level(zerolog.InfoLevel)
 	event := l2.Info()
-	event = withFieldsAndCaller(ctx, event, fields)
-	event.Msg(msg)
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
+}
+
+// write writes the entry with the specified level without exiting on FatalLevel.
+// The error is added if not nil.
+func (l *Logger) write(ctx context.Context, level Level, err error, msg string, fields ...any) {
+	if err != nil && level < l.levelFor(ctx) {
+		fields = slices.Concat(fields, Fields{zerolog.ErrorFieldName, err})
+	}
+	if l.buffered(ctx, level, msg, fields) || !l.sampled(ctx, level, msg) {
+		return
+	}
+	if level >= ErrorLevel {
+		Dump(ctx)
+	}
+	event := l.zerolog(ctx).WithLevel(zerolog.Level(level))
+	if err != nil {
+		event = l.withError(event, level, err)
+	}
+	event = l.withFieldsAndCaller(ctx, event, fields)
+	event.Msg(l.redactor.String(msg))
 }
 
 func DebugWithTrace(ctx context.Context, msg, trace string, fields ...any) {
@@ -152,9 +332,21 @@ func Force(ctx context.Context, msg string, fields ...any) {
 	FromContext(ctx).Force(ctx, msg, fields...)
 }
 
-func withFieldsAndCaller(ctx context.Context, event *zerolog.Event, f Fields) *zerolog.Event {
+func (l *Logger) withFieldsAndCaller(ctx context.Context, event *zerolog.Event, f Fields) *zerolog.Event {
 	if callerEnabled.Load() {
 		event = event.Caller(3) //nolint:mnd
 	}
-	return event.Fields([]any(ExtractFields(ctx).With(f)))
+	if l.name != "" {
+		event = event.Str(LoggerFieldName, l.name)
+	}
+	return event.Fields([]any(l.eventFields(ctx, f)))
+}
+
+// eventFields merges the bound fields, the context fields and the fields passed per call.
+func (l *Logger) eventFields(ctx context.Context, f Fields) Fields {
+	fields := ExtractFields(ctx).With(l.groupFields(f))
+	if len(l.fields) > 0 {
+		fields = l.fields.With(fields)
+	}
+	return l.redactor.Fields(fields)
 }
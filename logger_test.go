@@ -7,11 +7,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	. "github.com/cdnnow-pro/go-log"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type TestLogType struct {
@@ -44,6 +46,18 @@ func newTestLogType(data []byte) TestLogType {
 	return log
 }
 
+func decodeEntry(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+
+	entry := map[string]any{}
+	require.NoError(t, json.Unmarshal(data, &entry))
+	return entry
+}
+
+func splitLines(data []byte) [][]byte {
+	return bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+}
+
 func Test_JsonLog(t *testing.T) {
 	t.Parallel()
 
@@ -421,3 +435,70 @@ func TestParseLevel(t *testing.T) {
 		}
 	})
 }
+
+func TestLogger_With(t *testing.T) {
+	t.Run("bound fields", func(t *testing.T) {
+		// Arrange
+		ctx, buf := getTestData()
+		l := FromContext(ctx).With("component", "cache")
+
+		// Act
+		l.Info(ctx, "infoMsg", "with_field_1", "test")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, "cache", entry["component"])
+		assert.Equal(t, "test", entry["with_field_1"])
+	})
+
+	t.Run("through context", func(t *testing.T) {
+		// Arrange
+		ctx, buf := getTestData()
+		ctx = ToContext(ctx, FromContext(ctx).With("component", "cache"))
+
+		// Act
+		Info(ctx, "infoMsg")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, "cache", entry["component"])
+	})
+
+	t.Run("override with deduplication", func(t *testing.T) {
+		// Arrange
+		SetDeduplicationEnabled(true)
+		t.Cleanup(func() { SetDeduplicationEnabled(false) })
+		ctx, buf := getTestData()
+		l := FromContext(ctx).With("component", "cache", "key", "bound", "other", "bound")
+		ctx = InjectFields(ctx, "key", "context", "other", "context")
+
+		// Act
+		l.Info(ctx, "infoMsg", "other", "call")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, "cache", entry["component"])
+		assert.Equal(t, "context", entry["key"])
+		assert.Equal(t, "call", entry["other"])
+		assert.Equal(t, 1, strings.Count(buf.String(), `"key"`))
+		assert.Equal(t, 1, strings.Count(buf.String(), `"other"`))
+	})
+}
+
+func TestLogger_WithGroup(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx, buf := getTestData()
+	ctx = InjectFields(ctx, "request_id", "42")
+	l := FromContext(ctx).WithGroup("cache").With("size", 10).WithGroup("entry")
+
+	// Act
+	l.Info(ctx, "infoMsg", "key", "abc")
+
+	// Assert
+	entry := decodeEntry(t, buf.Bytes())
+	assert.Equal(t, "42", entry["request_id"])
+	assert.Equal(t, float64(10), entry["cache.size"])
+	assert.Equal(t, "abc", entry["cache.entry.key"])
+}
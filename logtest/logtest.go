@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: MIT
+
+// Package logtest provides an in-memory recorder of log entries for tests.
+//
+//	rec := logtest.New()
+//	ctx := rec.Context(context.Background(), log.DebugLevel)
+//	doSomething(ctx)
+//	rec.AssertLogged(t, log.InfoLevel, "done", "key", "value")
+package logtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
+)
+
+// Entry is a parsed log entry.
+type Entry struct {
+	Level   log.Level
+	Message string
+	Caller  string
+	Error   string
+	Time    string
+
+	// Fields holds all the other fields of the entry.
+	Fields map[string]any
+}
+
+// Field returns the value of the field and whether it is present.
+func (e Entry) Field(key string) (any, bool) {
+	v, ok := e.Fields[key]
+	return v, ok
+}
+
+// Entries is a list of entries in the order they were written.
+type Entries []Entry
+
+// FilterLevel returns the entries with the specified level.
+func (es Entries) FilterLevel(level log.Level) Entries {
+	return es.Filter(func(e Entry) bool { return e.Level == level })
+}
+
+// FilterMessage returns the entries with the specified message.
+func (es Entries) FilterMessage(msg string) Entries {
+	return es.Filter(func(e Entry) bool { return e.Message == msg })
+}
+
+// FilterField returns the entries having the field with the specified value.
+//
+// The value is compared after the JSON round trip, so e.g. int and float64 values are equal.
+func (es Entries) FilterField(key string, value any) Entries {
+	want := normalize(value)
+	return es.Filter(func(e Entry) bool {
+		v, ok := e.Fields[key]
+		return ok && reflect.DeepEqual(v, want)
+	})
+}
+
+// Filter returns the entries the function returns true for.
+func (es Entries) Filter(f func(Entry) bool) Entries {
+	var result Entries
+	for _, e := range es {
+		if f(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Recorder is an io.Writer that parses the JSON entries written by the logger and keeps them in memory.
+//
+// It is safe for concurrent use, so a single recorder may be shared by the parallel subtests.
+type Recorder struct {
+	mu      sync.Mutex
+	entries Entries
+	partial []byte
+}
+
+// New creates an empty recorder.
+func New() *Recorder {
+	return &Recorder{}
+}
+
+// Logger creates a logger writing to the recorder.
+func (r *Recorder) Logger(level log.Level, opts ...log.Option) *log.Logger {
+	return log.NewLogger(level, append(opts, log.WithOutput(r))...)
+}
+
+// Context stores a logger writing to the recorder to the context (see log.ToContext).
+func (r *Recorder) Context(ctx context.Context, level log.Level, opts ...log.Option) context.Context {
+	return log.ToContext(ctx, r.Logger(level, opts...))
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(data[:i]); len(line) > 0 {
+			e, err := parseEntry(line)
+			if err != nil {
+				r.partial = nil
+				return 0, err
+			}
+			r.entries = append(r.entries, e)
+		}
+		data = data[i+1:]
+	}
+	r.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// Entries returns a copy of the recorded entries.
+func (r *Recorder) Entries() Entries {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append(Entries(nil), r.entries...)
+}
+
+// FilterLevel returns the recorded entries with the specified level.
+func (r *Recorder) FilterLevel(level log.Level) Entries {
+	return r.Entries().FilterLevel(level)
+}
+
+// FilterField returns the recorded entries having the field with the specified value.
+func (r *Recorder) FilterField(key string, value any) Entries {
+	return r.Entries().FilterField(key, value)
+}
+
+// Reset removes all the recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = nil
+	r.partial = nil
+}
+
+// AssertLogged checks that an entry with the level, the message and the fields (key-value pairs) was recorded.
+// The entry may have other fields as well.
+func (r *Recorder) AssertLogged(t testing.TB, level log.Level, msg string, fields ...any) bool {
+	t.Helper()
+
+	entries := r.Entries()
+	found := entries.FilterLevel(level).FilterMessage(msg)
+	i := log.Fields(fields).Iterator()
+	for k, v, ok := i.Next(); ok; k, v, ok = i.Next() {
+		found = found.FilterField(k, v)
+	}
+	if len(found) > 0 {
+		return true
+	}
+
+	t.Errorf("no %s entry %q with fields %v, recorded entries:\n%s", level, msg, fields, entries)
+	return false
+}
+
+// AssertNotLogged checks that no entry with the level and the message was recorded.
+func (r *Recorder) AssertNotLogged(t testing.TB, level log.Level, msg string) bool {
+	t.Helper()
+
+	if found := r.Entries().FilterLevel(level).FilterMessage(msg); len(found) > 0 {
+		t.Errorf("unexpected %s entry %q recorded:\n%s", level, msg, found)
+		return false
+	}
+	return true
+}
+
+func (es Entries) String() string {
+	var sb strings.Builder
+	for _, e := range es {
+		fmt.Fprintf(&sb, "\t%s %q %v\n", e.Level, e.Message, e.Fields)
+	}
+	return sb.String()
+}
+
+var levels = map[string]log.Level{
+	log.TraceLevel.String(): log.TraceLevel,
+	log.DebugLevel.String(): log.DebugLevel,
+	log.InfoLevel.String():  log.InfoLevel,
+	log.WarnLevel.String():  log.WarnLevel,
+	log.ErrorLevel.String(): log.ErrorLevel,
+	log.FatalLevel.String(): log.FatalLevel,
+}
+
+func parseEntry(line []byte) (Entry, error) {
+	fields := map[string]any{}
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return Entry{}, fmt.Errorf("cannot parse log entry %q: %w", line, err)
+	}
+
+	e := Entry{Fields: fields}
+	if s, ok := popString(fields, zerolog.LevelFieldName); ok {
+		e.Level, ok = levels[strings.ToUpper(s)]
+		if !ok {
+			e.Level = log.Level(zerolog.NoLevel)
+		}
+	}
+	e.Message, _ = popString(fields, zerolog.MessageFieldName)
+	e.Caller, _ = popString(fields, zerolog.CallerFieldName)
+	e.Error, _ = popString(fields, zerolog.ErrorFieldName)
+	e.Time, _ = popString(fields, zerolog.TimestampFieldName)
+	return e, nil
+}
+
+func popString(fields map[string]any, key string) (string, bool) {
+	v, ok := fields[key]
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	if ok {
+		delete(fields, key)
+	}
+	return s, ok
+}
+
+// normalize converts the value to the form it has after the JSON round trip.
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var result any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return v
+	}
+	return result
+}
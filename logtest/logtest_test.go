@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+
+package logtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cdnnow-pro/go-log"
+	"github.com/cdnnow-pro/go-log/logtest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	rec := logtest.New()
+	ctx := rec.Context(context.Background(), log.DebugLevel)
+
+	t.Run("entries", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		log.Info(ctx, "entries info", "key", "value", "count", 3)
+		log.Error(ctx, errors.New("boom"), "entries error")
+
+		// Assert
+		rec.AssertLogged(t, log.InfoLevel, "entries info", "key", "value", "count", 3)
+		rec.AssertLogged(t, log.ErrorLevel, "entries error")
+		rec.AssertNotLogged(t, log.DebugLevel, "entries info")
+
+		errs := rec.FilterLevel(log.ErrorLevel).FilterMessage("entries error")
+		require.Len(t, errs, 1)
+		assert.Equal(t, "boom", errs[0].Error)
+	})
+
+	t.Run("filter field", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		log.Warn(ctx, "filter warn", "component", "cache")
+		log.Warn(ctx, "filter warn", "component", "router")
+
+		// Assert
+		found := rec.FilterField("component", "router")
+		require.Len(t, found, 1)
+		assert.Equal(t, log.WarnLevel, found[0].Level)
+		v, ok := found[0].Field("component")
+		assert.True(t, ok)
+		assert.Equal(t, "router", v)
+	})
+
+	t.Run("assert failure", func(t *testing.T) {
+		t.Parallel()
+
+		// Act
+		mock := &testing.T{}
+		ok := rec.AssertLogged(mock, log.InfoLevel, "never logged")
+
+		// Assert
+		assert.False(t, ok)
+		assert.True(t, mock.Failed())
+	})
+}
+
+func TestRecorder_PartialWrites(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+
+	// Act
+	_, err := rec.Write([]byte(`{"` + zerolog.LevelFieldName + `":"INFO","` + zerolog.MessageFieldName + `":"spl`))
+	require.NoError(t, err)
+	_, err = rec.Write([]byte("it\"}\n"))
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, rec.Entries(), 1)
+	assert.Equal(t, "split", rec.Entries()[0].Message)
+
+	// Act
+	rec.Reset()
+
+	// Assert
+	assert.Empty(t, rec.Entries())
+}
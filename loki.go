@@ -0,0 +1,538 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// LokiPushPath is the path of the Loki push API.
+const LokiPushPath = "/loki/api/v1/push"
+
+// LokiTenantHeader is the header of the tenant ID in the multi-tenant Loki.
+const LokiTenantHeader = "X-Scope-OrgID"
+
+const (
+	defaultLokiBatchBytes    = 1024 * 1024
+	defaultLokiBatchWait     = time.Second
+	defaultLokiMaxBufferSize = 16 * 1024 * 1024
+)
+
+// ErrLokiShutdown is returned by LokiWriter after Shutdown.
+var ErrLokiShutdown = errors.New("loki writer is shut down")
+
+// LokiEncoding is the payload encoding of the Loki push API.
+type LokiEncoding int8
+
+const (
+	// LokiProtobuf sends the snappy-compressed protobuf payload (application/x-protobuf).
+	LokiProtobuf LokiEncoding = iota
+	// LokiJSON sends the JSON payload (application/json).
+	LokiJSON
+)
+
+// LokiWriter is a zerolog.LevelWriter that pushes the entries to Grafana Loki.
+//
+// The fields in the label allowlist are promoted to the stream labels and removed from the line,
+// the rest of the entry is kept in the line as JSON. The entries that aren't JSON (e.g. written by WithPlainText)
+// are pushed as-is with the static labels and the level. A stream without labels gets the "job" label
+// with the executable name, since Loki requires at least one label.
+//
+// The entries are batched and pushed from a background goroutine in the order they were written,
+// and the timestamps in each stream never go backwards. When the buffered entries exceed the limit,
+// the new entries are dropped. Shutdown must be called to push the buffered entries.
+type LokiWriter struct {
+	pusher        httpPusher
+	encoding      LokiEncoding
+	labels        []string
+	staticLabels  map[string]string
+	batchBytes    int
+	batchWait     time.Duration
+	maxBufferSize int
+	errorHandler  func(error)
+
+	mu       sync.Mutex
+	closed   bool
+	streams  map[string]*lokiStream
+	order    []string
+	pending  int
+	buffered int
+	last     map[string]time.Time
+
+	ready   chan struct{}
+	flush   chan chan struct{}
+	stop    chan struct{}
+	dropped droppedCounter
+	ctx     context.Context //nolint:containedctx
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type lokiStream struct {
+	labels  map[string]string
+	entries []lokiEntry
+}
+
+type lokiEntry struct {
+	time time.Time
+	line string
+}
+
+type LokiOption func(*LokiWriter)
+
+// WithLokiEncoding sets the payload encoding (LokiProtobuf by default).
+func WithLokiEncoding(encoding LokiEncoding) LokiOption {
+	return func(w *LokiWriter) {
+		w.encoding = encoding
+	}
+}
+
+// WithLokiLabels sets the allowlist of the fields promoted to the stream labels
+// ("level", "service" and "logger" by default).
+//
+// The names that aren't valid label names are sanitized, e.g. "http.method" becomes the "http_method" label.
+// Keep the allowlist to the fields of low cardinality, every distinct set of values is a separate stream.
+func WithLokiLabels(fields ...string) LokiOption {
+	return func(w *LokiWriter) {
+		w.labels = fields
+	}
+}
+
+// WithLokiStaticLabels adds the labels to all the streams, e.g. "region" and "env".
+func WithLokiStaticLabels(labels map[string]string) LokiOption {
+	return func(w *LokiWriter) {
+		for name, value := range labels {
+			w.staticLabels[lokiLabelName(name)] = value
+		}
+	}
+}
+
+// WithLokiTenant sets the tenant ID sent in the X-Scope-OrgID header.
+func WithLokiTenant(tenant string) LokiOption {
+	return func(w *LokiWriter) {
+		w.pusher.headers.Set(LokiTenantHeader, tenant)
+	}
+}
+
+// WithLokiHeader adds the header to the push requests, e.g. for the authorization.
+func WithLokiHeader(key, value string) LokiOption {
+	return func(w *LokiWriter) {
+		w.pusher.headers.Add(key, value)
+	}
+}
+
+// WithLokiBatch sets the size of the lines in bytes that triggers the push and limits each push request
+// (1 MiB by default), and how long the entries wait for the batch to fill (1 second by default).
+func WithLokiBatch(size int, wait time.Duration) LokiOption {
+	return func(w *LokiWriter) {
+		if size > 0 {
+			w.batchBytes = size
+		}
+		if wait > 0 {
+			w.batchWait = wait
+		}
+	}
+}
+
+// WithLokiMaxBufferSize sets the size of the lines in bytes buffered until they are pushed (16 MiB by default).
+// The entries written when the buffer is full are dropped.
+func WithLokiMaxBufferSize(size int) LokiOption {
+	return func(w *LokiWriter) {
+		if size > 0 {
+			w.maxBufferSize = size
+		}
+	}
+}
+
+// WithLokiRetry sets the number of retries of a failed push and the exponential backoff between them
+// (5 retries, from 500 milliseconds up to 30 seconds by default).
+//
+// The requests are retried on network errors and on 429 and 5xx responses.
+// The Retry-After header of the response overrides the backoff.
+func WithLokiRetry(maxRetries int, initialBackoff, maxBackoff time.Duration) LokiOption {
+	return func(w *LokiWriter) {
+		w.pusher.maxRetries = maxRetries
+		w.pusher.initialBackoff = initialBackoff
+		w.pusher.maxBackoff = maxBackoff
+	}
+}
+
+// WithLokiHTTPClient sets the HTTP client of the push requests (a client with a 10 seconds timeout by default).
+func WithLokiHTTPClient(client *http.Client) LokiOption {
+	return func(w *LokiWriter) {
+		w.pusher.client = client
+	}
+}
+
+// WithLokiErrorHandler sets the function called when a batch can't be pushed.
+// The entries dropped on the full buffer are reported periodically (every 10 seconds) and on Shutdown.
+//
+// By default, the errors are printed to stderr.
+func WithLokiErrorHandler(f func(error)) LokiOption {
+	return func(w *LokiWriter) {
+		w.errorHandler = f
+	}
+}
+
+// NewLokiWriter creates a writer pushing to the Loki at the address (e.g. "http://loki:3100")
+// and starts its background goroutine.
+func NewLokiWriter(addr string, opts ...LokiOption) *LokiWriter {
+	w := &LokiWriter{
+		pusher:        newHTTPPusher(strings.TrimRight(addr, "/")+LokiPushPath, lokiRetryable),
+		labels:        []string{zerolog.LevelFieldName, "service", LoggerFieldName},
+		staticLabels:  map[string]string{},
+		batchBytes:    defaultLokiBatchBytes,
+		batchWait:     defaultLokiBatchWait,
+		maxBufferSize: defaultLokiMaxBufferSize,
+		errorHandler: func(err error) {
+			_, _ = fmt.Fprintf(os.Stderr, "loki: %v\n", err)
+		},
+		streams: map[string]*lokiStream{},
+		last:    map[string]time.Time{},
+		ready:   make(chan struct{}, 1),
+		flush:   make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *LokiWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *LokiWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	labels, e := w.entry(level, p)
+	key := lokiLabelsString(labels)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrLokiShutdown
+	}
+	if w.buffered+len(e.line) > w.maxBufferSize {
+		w.dropped.drop()
+		return len(p), nil
+	}
+
+	// Loki rejects the entries older than the last one of the stream.
+	if last := w.last[key]; e.time.Before(last) {
+		e.time = last
+	}
+	w.last[key] = e.time
+
+	s, ok := w.streams[key]
+	if !ok {
+		s = &lokiStream{labels: labels}
+		w.streams[key] = s
+		w.order = append(w.order, key)
+	}
+	s.entries = append(s.entries, e)
+	w.pending += len(e.line)
+	w.buffered += len(e.line)
+
+	if w.pending >= w.batchBytes {
+		select {
+		case w.ready <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Dropped returns the total number of the entries dropped on the full buffer or after the failed push.
+func (w *LokiWriter) Dropped() uint64 {
+	return w.dropped.load()
+}
+
+// ForceFlush pushes the buffered entries and waits for the push to finish or the context to be done.
+func (w *LokiWriter) ForceFlush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case w.flush <- flushed:
+	case <-w.done:
+		return ErrLokiShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown pushes the buffered entries and stops the background goroutine.
+// If the context is done first, the pending push is cancelled and the context error is returned.
+//
+// Entries written after Shutdown are rejected with ErrLokiShutdown.
+func (w *LokiWriter) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.stop)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+func (w *LokiWriter) run() {
+	defer close(w.done)
+	defer w.cancel()
+
+	defer w.dropped.report(w.errorHandler, "buffer")
+
+	ticker := time.NewTicker(w.batchWait)
+	defer ticker.Stop()
+	report := time.NewTicker(dropReportInterval)
+	defer report.Stop()
+
+	for {
+		select {
+		case <-w.ready:
+			w.push()
+		case <-ticker.C:
+			w.push()
+		case <-report.C:
+			w.dropped.report(w.errorHandler, "buffer")
+		case flushed := <-w.flush:
+			w.push()
+			close(flushed)
+		case <-w.stop:
+			w.push()
+			return
+		}
+	}
+}
+
+// push sends the buffered streams in the requests of at most batchBytes of the lines each.
+// Their size is kept in the buffer until the push is finished, so the memory of the pushed batch is bounded too.
+func (w *LokiWriter) push() {
+	w.mu.Lock()
+	streams := make([]*lokiStream, 0, len(w.order))
+	for _, key := range w.order {
+		streams = append(streams, w.streams[key])
+	}
+	size := w.pending
+	w.streams = map[string]*lokiStream{}
+	w.order = nil
+	w.pending = 0
+	w.mu.Unlock()
+
+	for _, batch := range splitLokiStreams(streams, w.batchBytes) {
+		body, header, err := w.encode(batch)
+		if err == nil {
+			err = w.pusher.push(w.ctx, body, header)
+		}
+		if err != nil {
+			entries := 0
+			for _, s := range batch {
+				entries += len(s.entries)
+			}
+			w.dropped.add(entries)
+			w.errorHandler(fmt.Errorf("push of %d log entries failed: %w", entries, err))
+		}
+	}
+
+	w.mu.Lock()
+	w.buffered -= size
+	w.mu.Unlock()
+}
+
+// splitLokiStreams splits the streams into the batches with at most maxBytes of the lines.
+// A stream is split between the batches if needed, and an entry larger than maxBytes is sent alone.
+func splitLokiStreams(streams []*lokiStream, maxBytes int) [][]*lokiStream {
+	var (
+		batches [][]*lokiStream
+		batch   []*lokiStream
+		size    int
+	)
+	for _, s := range streams {
+		var part *lokiStream
+		for _, e := range s.entries {
+			if size > 0 && size+len(e.line) > maxBytes {
+				batches = append(batches, batch)
+				batch, part, size = nil, nil, 0
+			}
+			if part == nil {
+				part = &lokiStream{labels: s.labels}
+				batch = append(batch, part)
+			}
+			part.entries = append(part.entries, e)
+			size += len(e.line)
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// entry returns the labels and the line of the entry.
+func (w *LokiWriter) entry(level zerolog.Level, p []byte) (map[string]string, lokiEntry) {
+	labels := make(map[string]string, len(w.staticLabels)+len(w.labels))
+	for name, value := range w.staticLabels {
+		labels[name] = value
+	}
+	e := lokiEntry{time: time.Now()}
+
+	fields, err := parseJSONFields(p)
+	if err != nil {
+		e.line = strings.TrimRight(string(p), "\n")
+	} else {
+		line := make([]jsonField, 0, len(fields))
+		for _, f := range fields {
+			if f.key == zerolog.TimestampFieldName {
+				var s string
+				if json.Unmarshal(f.raw, &s) == nil {
+					if t, err := time.Parse(zerolog.TimeFieldFormat, s); err == nil {
+						e.time = t
+					}
+				}
+			}
+			if !slices.Contains(w.labels, f.key) {
+				line = append(line, f)
+				continue
+			}
+			value, err := logfmtValue(f.raw)
+			if err != nil {
+				value = string(f.raw)
+			}
+			labels[lokiLabelName(f.key)] = value
+		}
+		e.line = string(appendJSONObject(nil, line))
+	}
+
+	if level != zerolog.NoLevel && slices.Contains(w.labels, zerolog.LevelFieldName) {
+		if _, ok := labels[zerolog.LevelFieldName]; !ok {
+			labels[zerolog.LevelFieldName] = Level(level).String()
+		}
+	}
+	if len(labels) == 0 {
+		labels["job"] = filepath.Base(os.Args[0])
+	}
+	return labels, e
+}
+
+func (w *LokiWriter) encode(streams []*lokiStream) ([]byte, http.Header, error) {
+	header := http.Header{}
+	if w.encoding == LokiJSON {
+		header.Set("Content-Type", "application/json")
+		body, err := marshalLokiJSON(streams)
+		return body, header, err
+	}
+
+	header.Set("Content-Type", "application/x-protobuf")
+	return snappy.Encode(nil, appendLokiProto(nil, streams)), header, nil
+}
+
+func lokiRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// lokiLabelName returns the valid label name: letters, digits and underscores, not starting with a digit.
+func lokiLabelName(key string) string {
+	name := []byte(key)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= '0' && c <= '9' && i > 0) {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// lokiLabelsString returns the labels in the Prometheus format, e.g. {level="INFO", service="edge"}.
+func lokiLabelsString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// appendLokiProto appends the logproto.PushRequest.
+func appendLokiProto(b []byte, streams []*lokiStream) []byte {
+	for _, s := range streams {
+		stream := protowire.AppendTag(nil, 1, protowire.BytesType)
+		stream = protowire.AppendString(stream, lokiLabelsString(s.labels))
+		for _, e := range s.entries {
+			ts := protowire.AppendTag(nil, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.time.Unix())) //nolint:gosec
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.time.Nanosecond()))
+
+			entry := appendProtoMessage(nil, 1, ts)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendString(entry, e.line)
+
+			stream = appendProtoMessage(stream, 2, entry)
+		}
+		b = appendProtoMessage(b, 1, stream)
+	}
+	return b
+}
+
+func marshalLokiJSON(streams []*lokiStream) ([]byte, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	req := struct {
+		Streams []stream `json:"streams"`
+	}{Streams: make([]stream, 0, len(streams))}
+	for _, s := range streams {
+		values := make([][2]string, 0, len(s.entries))
+		for _, e := range s.entries {
+			values = append(values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, stream{Stream: s.labels, Values: values})
+	}
+	return json.Marshal(req)
+}
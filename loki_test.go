@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lokiPushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiWriter_Protobuf(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewLokiWriter(srv.URL, WithLokiTenant("cdn"), WithLokiLabels("level", "service", "http.method"))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "served", "service", "edge", "http.method", "GET", "request_id", "r1")
+	l.Warn(context.Background(), "slow", "service", "edge", "http.method", "GET")
+	l.Info(context.Background(), "purged", "service", "edge", "http.method", "POST")
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	requests, bodies := c.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, LokiPushPath, requests[0].URL.Path)
+	assert.Equal(t, "cdn", requests[0].Header.Get(LokiTenantHeader))
+	assert.Equal(t, "application/x-protobuf", requests[0].Header.Get("Content-Type"))
+
+	body, err := snappy.Decode(nil, bodies[0])
+	require.NoError(t, err)
+	req := decodeProto(t, body)
+	require.Len(t, req[1], 3)
+
+	lines := map[string][]string{}
+	for i := range req[1] {
+		stream := req.message(t, 1, i)
+		labels := string(stream[1][0].([]byte))
+		for j := range stream[2] {
+			entry := stream.message(t, 2, j)
+			assert.NotZero(t, entry.message(t, 1, 0)[1][0])
+			lines[labels] = append(lines[labels], string(entry[2][0].([]byte)))
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		`{http_method="GET", level="INFO", service="edge"}`:  {`{"request_id":"r1","message":"served"}`},
+		`{http_method="GET", level="WARN", service="edge"}`:  {`{"message":"slow"}`},
+		`{http_method="POST", level="INFO", service="edge"}`: {`{"message":"purged"}`},
+	}, lines)
+}
+
+func TestLokiWriter_JSON(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewLokiWriter(srv.URL, WithLokiEncoding(LokiJSON), WithLokiStaticLabels(map[string]string{"region": "eu"}))
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutput(w)).Named("cache")
+	before := time.Now().Add(-time.Second)
+
+	// Act
+	l.Debug(context.Background(), "hit", "key", "/img")
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	requests, bodies := c.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+
+	var req lokiPushRequest
+	require.NoError(t, json.Unmarshal(bodies[0], &req))
+	require.Len(t, req.Streams, 1)
+	assert.Equal(t, map[string]string{"region": "eu", "level": "DEBUG", "logger": "cache"}, req.Streams[0].Stream)
+	require.Len(t, req.Streams[0].Values, 1)
+	ts, err := strconv.ParseInt(req.Streams[0].Values[0][0], 10, 64)
+	require.NoError(t, err)
+	assert.Greater(t, ts, before.UnixNano())
+	assert.Contains(t, req.Streams[0].Values[0][1], `"message":"hit"`)
+	assert.Contains(t, req.Streams[0].Values[0][1], `"key":"/img"`)
+	assert.NotContains(t, req.Streams[0].Values[0][1], `"level"`)
+}
+
+func TestLokiWriter_PlainText(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewLokiWriter(srv.URL, WithLokiEncoding(LokiJSON), WithLokiStaticLabels(map[string]string{"service": "edge"}))
+	l := NewLogger(DebugLevel, WithPlainText(w))
+
+	// Act
+	l.Info(context.Background(), "started", "port", 8080)
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	_, bodies := c.received()
+	require.Len(t, bodies, 1)
+	var req lokiPushRequest
+	require.NoError(t, json.Unmarshal(bodies[0], &req))
+	require.Len(t, req.Streams, 1)
+	assert.Equal(t, map[string]string{"service": "edge"}, req.Streams[0].Stream)
+	require.Len(t, req.Streams[0].Values, 1)
+	assert.Contains(t, req.Streams[0].Values[0][1], "started")
+	assert.Contains(t, req.Streams[0].Values[0][1], "8080")
+	assert.NotContains(t, req.Streams[0].Values[0][1], "\n")
+}
+
+func TestLokiWriter_Order(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewLokiWriter(srv.URL, WithLokiEncoding(LokiJSON))
+
+	// Act
+	for _, entry := range []string{
+		`{"level":"INFO","time":"2024-05-01T10:00:02.000Z","message":"first"}`,
+		`{"level":"INFO","time":"2024-05-01T10:00:01.000Z","message":"second"}`,
+		`{"level":"INFO","time":"2024-05-01T10:00:03.000Z","message":"third"}`,
+	} {
+		_, err := w.Write([]byte(entry + "\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	_, bodies := c.received()
+	require.Len(t, bodies, 1)
+	var req lokiPushRequest
+	require.NoError(t, json.Unmarshal(bodies[0], &req))
+	require.Len(t, req.Streams, 1)
+	values := req.Streams[0].Values
+	require.Len(t, values, 3)
+	assert.Equal(t, strconv.FormatInt(time.Date(2024, 5, 1, 10, 0, 2, 0, time.UTC).UnixNano(), 10), values[0][0])
+	assert.Equal(t, values[0][0], values[1][0])
+	assert.Equal(t, strconv.FormatInt(time.Date(2024, 5, 1, 10, 0, 3, 0, time.UTC).UnixNano(), 10), values[2][0])
+	assert.Contains(t, values[1][1], "second")
+}
+
+func TestLokiWriter_Retry(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, func(n int) int {
+		switch n {
+		case 1:
+			return http.StatusTooManyRequests
+		case 2:
+			return http.StatusInternalServerError
+		default:
+			return http.StatusNoContent
+		}
+	})
+	w := NewLokiWriter(srv.URL, WithLokiRetry(3, time.Millisecond, 10*time.Millisecond))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "retried")
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	requests, _ := c.received()
+	assert.Len(t, requests, 3)
+	assert.Zero(t, w.Dropped())
+}
+
+func TestLokiWriter_MaxBufferSize(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	var errs []error
+	w := NewLokiWriter(srv.URL, WithLokiEncoding(LokiJSON), WithLokiBatch(1024*1024, time.Hour),
+		WithLokiMaxBufferSize(120), WithLokiErrorHandler(func(err error) { errs = append(errs, err) }))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	for range 5 {
+		l.Info(context.Background(), "entry", "payload", strings.Repeat("x", 20))
+	}
+	require.NoError(t, w.ForceFlush(context.Background()))
+	l.Info(context.Background(), "after flush")
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	_, bodies := c.received()
+	require.Len(t, bodies, 2)
+	var req lokiPushRequest
+	require.NoError(t, json.Unmarshal(bodies[0], &req))
+	require.Len(t, req.Streams, 1)
+	assert.Len(t, req.Streams[0].Values, 2)
+	assert.Equal(t, uint64(3), w.Dropped())
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "buffer is full, 3 log entries dropped")
+}
+
+func TestLokiWriter_BatchBytes(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewLokiWriter(srv.URL, WithLokiEncoding(LokiJSON), WithLokiBatch(100, time.Hour))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	for i := range 10 {
+		if i%2 == 0 {
+			l.Info(context.Background(), "entry", "n", i)
+		} else {
+			l.Warn(context.Background(), "entry", "n", i)
+		}
+	}
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	_, bodies := c.received()
+	require.Greater(t, len(bodies), 1)
+	entries := 0
+	for _, body := range bodies {
+		var req lokiPushRequest
+		require.NoError(t, json.Unmarshal(body, &req))
+		size := 0
+		for _, s := range req.Streams {
+			for _, v := range s.Values {
+				size += len(v[1])
+				entries++
+			}
+		}
+		assert.LessOrEqual(t, size, 100)
+	}
+	assert.Equal(t, 10, entries)
+}
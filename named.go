@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// LoggerFieldName is the field name for the name of the loggers created with Named.
+const LoggerFieldName = "logger"
+
+// levelRules holds the parsed level spec (see SetLevelSpec).
+var levelRules atomic.Pointer[levelSpec]
+
+type levelSpec struct {
+	// fallback is the level for the named loggers without a matching rule.
+	fallback    Level
+	hasFallback bool
+
+	exact    map[string]Level
+	prefixes []levelPrefix
+}
+
+type levelPrefix struct {
+	prefix string
+	level  Level
+}
+
+// Named creates a child of the default logger with the specified name, see Logger.Named.
+//
+// The logger writes with the default logger current at the time of writing, so it may be
+// created before Init (e.g. in a package-level variable). Level and With without deduplication
+// (see SetDeduplicationEnabled) bind the child to the default logger current at the time of the call.
+func Named(name string) *Logger {
+	l := &Logger{l: contextZerolog(context.Background()), followsDefault: true}
+	return l.Named(name)
+}
+
+// Named creates a child logger with the specified name. The names of the nested loggers are joined with a dot.
+//
+// The name is added to every entry as the "logger" field and is used to select the level from
+// the level spec (see SetLevelSpec). If the spec has a rule for the name, then it overrides
+// the level of the logger.
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+
+	c := *l
+	if c.name == "" {
+		c.name = name
+	} else {
+		c.name += "." + name
+	}
+	return &c
+}
+
+// Name returns the name of the logger set with Named.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// SetLevelSpec sets the levels of the named loggers (see Named) from the spec,
+// which may be called again at any time to reload the rules.
+//
+// The spec is a comma-separated list of "name=level" rules, e.g. "info,router=debug,cache=warn".
+// A rule without a name sets the level of the named loggers not matched by any other rule.
+// A name ending with "*" matches all the names with that prefix ("http.*" matches "http.router").
+// The exact name takes precedence over the prefixes, and the longest prefix takes precedence over shorter ones.
+//
+// The empty spec removes all the rules. The loggers without a name are not affected.
+func SetLevelSpec(spec string) error {
+	s, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	levelRules.Store(s)
+	return nil
+}
+
+func parseLevelSpec(spec string) (*levelSpec, error) {
+	s := &levelSpec{exact: map[string]Level{}}
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		name, levelStr, found := strings.Cut(rule, "=")
+		if !found {
+			name, levelStr = "", name
+		}
+		name = strings.TrimSpace(name)
+
+		level, err := ParseLevel(strings.TrimSpace(levelStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level spec rule %q: %w", rule, err)
+		}
+
+		switch {
+		case !found:
+			s.fallback, s.hasFallback = level, true
+		case name == "" || name == "*":
+			return nil, fmt.Errorf("invalid level spec rule %q: empty name", rule)
+		case strings.HasSuffix(name, "*"):
+			s.prefixes = append(s.prefixes, levelPrefix{prefix: strings.TrimSuffix(name, "*"), level: level})
+		default:
+			s.exact[name] = level
+		}
+	}
+	return s, nil
+}
+
+func namedLevel(name string) (Level, bool) {
+	s := levelRules.Load()
+	if s == nil {
+		return 0, false
+	}
+	return s.level(name)
+}
+
+func (s *levelSpec) level(name string) (Level, bool) {
+	if level, ok := s.exact[name]; ok {
+		return level, true
+	}
+
+	var (
+		best  Level
+		found = -1
+	)
+	for _, p := range s.prefixes {
+		if len(p.prefix) > found && strings.HasPrefix(name, p.prefix) {
+			best, found = p.level, len(p.prefix)
+		}
+	}
+	if found >= 0 {
+		return best, true
+	}
+
+	return s.fallback, s.hasFallback
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Named(t *testing.T) {
+	// Arrange
+	require.NoError(t, SetLevelSpec("warn, router=debug, http.*=trace, cache=error"))
+	defer SetLevelSpec("")
+	ctx, buf := getTestData()
+	l := FromContext(ctx).Level(InfoLevel)
+
+	t.Run("name field", func(t *testing.T) {
+		// Act
+		l.Named("router").Info(ctx, "infoMsg")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, "router", entry[LoggerFieldName])
+		buf.Reset()
+	})
+
+	t.Run("exact rule", func(t *testing.T) {
+		// Act
+		l.Named("router").Debug(ctx, "visible")
+		l.Named("cache").Warn(ctx, "hidden")
+
+		// Assert
+		assert.Contains(t, buf.String(), "visible")
+		assert.NotContains(t, buf.String(), "hidden")
+		buf.Reset()
+	})
+
+	t.Run("prefix rule", func(t *testing.T) {
+		// Act
+		n := l.Named("http").Named("router")
+		n.DebugWithTrace(ctx, "visible", "traceData")
+
+		// Assert
+		assert.Equal(t, "http.router", n.Name())
+		assert.Contains(t, buf.String(), "visible")
+		assert.Contains(t, buf.String(), "traceData")
+		buf.Reset()
+	})
+
+	t.Run("fallback rule", func(t *testing.T) {
+		// Act
+		l.Named("other").Info(ctx, "hidden")
+		l.Info(ctx, "unnamed")
+
+		// Assert
+		assert.NotContains(t, buf.String(), "hidden")
+		assert.Contains(t, buf.String(), "unnamed")
+		buf.Reset()
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		// Arrange
+		n := l.Named("cache")
+
+		// Act
+		require.NoError(t, SetLevelSpec("cache=debug"))
+		n.Debug(ctx, "visible")
+
+		// Assert
+		assert.Contains(t, buf.String(), "visible")
+		buf.Reset()
+	})
+}
+
+func TestNamed_BeforeInit(t *testing.T) {
+	// Arrange
+	n := Named("db")
+	var buf bytes.Buffer
+	Init(InfoLevel, WithOutput(&buf))
+	defer Init(InfoLevel)
+
+	// Act
+	n.Info(context.Background(), "connected")
+	SetGlobalLevel(DebugLevel)
+	n.Debug(context.Background(), "queried")
+
+	// Assert
+	lines := splitLines(buf.Bytes())
+	require.Len(t, lines, 2)
+	for i, msg := range []string{"connected", "queried"} {
+		entry := decodeEntry(t, lines[i])
+		assert.Equal(t, "db", entry[LoggerFieldName])
+		assert.Equal(t, msg, entry["message"])
+	}
+}
+
+func TestSetLevelSpec_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{"router=verbose", "=debug", "*=debug"} {
+		assert.Error(t, SetLevelSpec(spec), spec)
+	}
+}
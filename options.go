@@ -18,6 +18,14 @@ func WithHook(h zerolog.Hook) Option {
 	}
 }
 
+// WithRedaction removes the sensitive data from the fields (bound, injected to the context and passed per call),
+// the messages and the errors with the redactor.
+func WithRedaction(r *Redactor) Option {
+	return func(l *Logger) {
+		l.redactor = r
+	}
+}
+
 func WithTimestamp() Option {
 	return func(l *Logger) {
 		zl := l.l.With().Timestamp().Logger()
@@ -41,18 +49,21 @@ func WithPlainText(w ...io.Writer) Option {
 		w = []io.Writer{os.Stdout}
 	}
 
+	return WithOutput(newPlainTextWriter(w[0]))
+}
+
+// newPlainTextWriter creates the plain text writer of WithPlainText to the output.
+func newPlainTextWriter(out io.Writer) zerolog.ConsoleWriter {
 	partsOrder := []string{
 		zerolog.LevelFieldName,
 		zerolog.CallerFieldName,
 		zerolog.MessageFieldName,
 	}
 
-	writer := zerolog.NewConsoleWriter(func(writer *zerolog.ConsoleWriter) {
+	return zerolog.NewConsoleWriter(func(writer *zerolog.ConsoleWriter) {
 		writer.PartsOrder = partsOrder
-		writer.Out = w[0]
+		writer.Out = out
 	})
-
-	return WithOutput(writer)
 }
 
 // WithPlainTextAndTimestamp creates an output writer with the plain text format instead of JSON.
@@ -83,6 +94,17 @@ func WithPlainTextAndTimestamp(timeFormat string, w ...io.Writer) Option {
 	return WithOutput(writer)
 }
 
+// WithLogfmt creates an output writer with the logfmt format instead of JSON, see LogfmtWriter.
+//
+// Optionally the inner output writer could be specified as second argument.
+// Otherwise, the os.Stdout will be used.
+func WithLogfmt(w ...io.Writer) Option {
+	if len(w) == 0 {
+		w = []io.Writer{os.Stdout}
+	}
+	return WithOutput(NewLogfmtWriter(w[0]))
+}
+
 type GrpcOption func(*grpcLogger)
 
 func WithGrpcTimestamp() GrpcOption {
@@ -99,6 +121,24 @@ func WithGrpcOutput(w io.Writer) GrpcOption {
 	}
 }
 
+// WithGrpcVerbosity sets the verbosity threshold reported by V, in the same way as
+// the GRPC_GO_LOG_VERBOSITY_LEVEL environment variable for the default gRPC logger.
+func WithGrpcVerbosity(verbosity int) GrpcOption {
+	return func(l *grpcLogger) {
+		l.verbosity = verbosity
+	}
+}
+
+// WithGrpcComponentFilter sets the filter for the entries of gRPC components (e.g. "transport").
+// The filter returns the level to write the entry with, or false to drop the entry.
+//
+// Fatal entries are not filtered, the levels above ErrorLevel returned by the filter are lowered to ErrorLevel.
+func WithGrpcComponentFilter(f func(component string, level Level) (Level, bool)) GrpcOption {
+	return func(l *grpcLogger) {
+		l.filter = f
+	}
+}
+
 // WithGrpcPlainText creates an output writer with the plain text format instead of JSON.
 //
 // Optionally the inner output writer could be specified as second argument.
@@ -124,3 +164,14 @@ func WithGrpcPlainText(withTimestamp bool, w ...io.Writer) GrpcOption {
 
 	return WithGrpcOutput(writer)
 }
+
+// WithGrpcLogfmt creates an output writer with the logfmt format instead of JSON, see LogfmtWriter.
+//
+// Optionally the inner output writer could be specified as second argument.
+// Otherwise, the os.Stdout will be used.
+func WithGrpcLogfmt(w ...io.Writer) GrpcOption {
+	if len(w) == 0 {
+		w = []io.Writer{os.Stdout}
+	}
+	return WithGrpcOutput(NewLogfmtWriter(w[0]))
+}
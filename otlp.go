@@ -0,0 +1,350 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultOTLPEndpoint is the default logs endpoint of the OTLP/HTTP collector.
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/logs"
+
+// OTLPScopeName is the instrumentation scope of the exported log records.
+const OTLPScopeName = "github.com/cdnnow-pro/go-log"
+
+const (
+	defaultOTLPBatchSize     = 512
+	defaultOTLPQueueSize     = 8192
+	defaultOTLPFlushInterval = time.Second
+)
+
+// ErrOTLPShutdown is returned by OTLPWriter after Shutdown.
+var ErrOTLPShutdown = errors.New("otlp writer is shut down")
+
+// OTLPEncoding is the payload encoding of OTLP/HTTP.
+type OTLPEncoding int8
+
+const (
+	// OTLPProtobuf sends the binary protobuf payload (application/x-protobuf).
+	OTLPProtobuf OTLPEncoding = iota
+	// OTLPJSON sends the JSON payload (application/json).
+	OTLPJSON
+)
+
+// OTLPWriter is a zerolog.LevelWriter that exports the entries to the OpenTelemetry collector with OTLP/HTTP.
+//
+// Each entry becomes a LogRecord with the keys mapped as ProfileOTel does: the level is the severity,
+// the message is the body, "trace_id" and "span_id" (set by hooks/trace) are the trace context,
+// and the rest of the fields are the attributes.
+//
+// The records are queued and exported in batches from a background goroutine, so Write never blocks on the network.
+// When the queue is full, the records are dropped. Shutdown must be called to export the queued records.
+type OTLPWriter struct {
+	pusher        httpPusher
+	encoding      OTLPEncoding
+	gzip          bool
+	batchSize     int
+	flushInterval time.Duration
+	resource      []otlpKeyValue
+	errorHandler  func(error)
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan otlpRecord
+	flush  chan chan struct{}
+
+	dropped droppedCounter
+	ctx     context.Context //nolint:containedctx
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type OTLPOption func(*OTLPWriter)
+
+// WithOTLPEncoding sets the payload encoding (OTLPProtobuf by default).
+func WithOTLPEncoding(encoding OTLPEncoding) OTLPOption {
+	return func(w *OTLPWriter) {
+		w.encoding = encoding
+	}
+}
+
+// WithOTLPHeader adds the header to the export requests, e.g. for the authorization.
+func WithOTLPHeader(key, value string) OTLPOption {
+	return func(w *OTLPWriter) {
+		w.pusher.headers.Add(key, value)
+	}
+}
+
+// WithOTLPGzip enables the gzip compression of the export requests.
+func WithOTLPGzip() OTLPOption {
+	return func(w *OTLPWriter) {
+		w.gzip = true
+	}
+}
+
+// WithOTLPBatchSize sets the maximum number of records in one export request (512 by default).
+func WithOTLPBatchSize(size int) OTLPOption {
+	return func(w *OTLPWriter) {
+		if size > 0 {
+			w.batchSize = size
+		}
+	}
+}
+
+// WithOTLPQueueSize sets the number of records the queue can hold (8192 by default).
+func WithOTLPQueueSize(size int) OTLPOption {
+	return func(w *OTLPWriter) {
+		if size > 0 {
+			w.queue = make(chan otlpRecord, size)
+		}
+	}
+}
+
+// WithOTLPFlushInterval sets how long the records wait for a full batch (1 second by default).
+func WithOTLPFlushInterval(interval time.Duration) OTLPOption {
+	return func(w *OTLPWriter) {
+		if interval > 0 {
+			w.flushInterval = interval
+		}
+	}
+}
+
+// WithOTLPRetry sets the number of retries of a failed export and the exponential backoff between them
+// (5 retries, from 500 milliseconds up to 30 seconds by default).
+//
+// The requests are retried on network errors and on 429, 502, 503 and 504 responses.
+// The Retry-After header of the response overrides the backoff.
+func WithOTLPRetry(maxRetries int, initialBackoff, maxBackoff time.Duration) OTLPOption {
+	return func(w *OTLPWriter) {
+		w.pusher.maxRetries = maxRetries
+		w.pusher.initialBackoff = initialBackoff
+		w.pusher.maxBackoff = maxBackoff
+	}
+}
+
+// WithOTLPResource sets the resource attributes as key-value pairs, e.g. "service.name", "cdn-edge".
+func WithOTLPResource(attrs ...any) OTLPOption {
+	return func(w *OTLPWriter) {
+		for i := 0; i < len(attrs); i += 2 {
+			var value any = "!MISSING"
+			if i+1 < len(attrs) {
+				value = attrs[i+1]
+			}
+			w.resource = append(w.resource, otlpKeyValue{key: fmt.Sprint(attrs[i]), value: otlpValueOf(value)})
+		}
+	}
+}
+
+// WithOTLPHTTPClient sets the HTTP client of the export requests (a client with a 10 seconds timeout by default).
+func WithOTLPHTTPClient(client *http.Client) OTLPOption {
+	return func(w *OTLPWriter) {
+		w.pusher.client = client
+	}
+}
+
+// WithOTLPErrorHandler sets the function called when a batch can't be exported.
+// The records dropped on the full queue are reported periodically (every 10 seconds) and on Shutdown.
+//
+// By default, the errors are printed to stderr.
+func WithOTLPErrorHandler(f func(error)) OTLPOption {
+	return func(w *OTLPWriter) {
+		w.errorHandler = f
+	}
+}
+
+// NewOTLPWriter creates a writer exporting to the OTLP/HTTP logs endpoint (e.g. DefaultOTLPEndpoint)
+// and starts its background goroutine.
+func NewOTLPWriter(endpoint string, opts ...OTLPOption) *OTLPWriter {
+	w := &OTLPWriter{
+		pusher:        newHTTPPusher(endpoint, otlpRetryable),
+		batchSize:     defaultOTLPBatchSize,
+		flushInterval: defaultOTLPFlushInterval,
+		errorHandler: func(err error) {
+			_, _ = fmt.Fprintf(os.Stderr, "otlp: %v\n", err)
+		},
+		queue: make(chan otlpRecord, defaultOTLPQueueSize),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *OTLPWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *OTLPWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	r, err := newOTLPRecord(level, p, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, ErrOTLPShutdown
+	}
+
+	select {
+	case w.queue <- r:
+	default:
+		w.dropped.drop()
+	}
+	return len(p), nil
+}
+
+// Dropped returns the total number of the records dropped on the full queue or after the failed export.
+func (w *OTLPWriter) Dropped() uint64 {
+	return w.dropped.load()
+}
+
+// ForceFlush exports the queued records and waits for the export to finish or the context to be done.
+func (w *OTLPWriter) ForceFlush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case w.flush <- flushed:
+	case <-w.done:
+		return ErrOTLPShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the queued records and stops the background goroutine.
+// If the context is done first, the pending export is cancelled and the context error is returned.
+//
+// Entries written after Shutdown are rejected with ErrOTLPShutdown.
+func (w *OTLPWriter) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+func (w *OTLPWriter) run() {
+	defer close(w.done)
+	defer w.cancel()
+	defer w.dropped.report(w.errorHandler, "queue")
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+	report := time.NewTicker(dropReportInterval)
+	defer report.Stop()
+
+	batch := make([]otlpRecord, 0, w.batchSize)
+	export := func() {
+		if len(batch) > 0 {
+			w.export(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case r, ok := <-w.queue:
+			if !ok {
+				export()
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) == w.batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case <-report.C:
+			w.dropped.report(w.errorHandler, "queue")
+		case flushed := <-w.flush:
+			for len(w.queue) > 0 {
+				r, ok := <-w.queue
+				if !ok {
+					break
+				}
+				batch = append(batch, r)
+				if len(batch) == w.batchSize {
+					export()
+				}
+			}
+			export()
+			close(flushed)
+		}
+	}
+}
+
+// export sends the batch and counts its records as dropped if it fails.
+func (w *OTLPWriter) export(batch []otlpRecord) {
+	body, header, err := w.encode(batch)
+	if err == nil {
+		err = w.pusher.push(w.ctx, body, header)
+	}
+	if err != nil {
+		w.dropped.add(len(batch))
+		w.errorHandler(fmt.Errorf("export of %d log records failed: %w", len(batch), err))
+	}
+}
+
+func (w *OTLPWriter) encode(batch []otlpRecord) ([]byte, http.Header, error) {
+	header := http.Header{}
+	var (
+		body []byte
+		err  error
+	)
+	switch w.encoding {
+	case OTLPJSON:
+		body, err = marshalOTLPRequestJSON(w.resource, OTLPScopeName, batch)
+		header.Set("Content-Type", "application/json")
+	default:
+		body = appendOTLPRequestProto(nil, w.resource, OTLPScopeName, batch)
+		header.Set("Content-Type", "application/x-protobuf")
+	}
+	if err != nil || !w.gzip {
+		return body, header, err
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	body, err = gzipBytes(body)
+	return body, header, err
+}
+
+func otlpRetryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
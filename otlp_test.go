@@ -0,0 +1,339 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// collector is a stand-in OTLP/HTTP collector recording the request bodies.
+type collector struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newCollector(t *testing.T, status func(n int) int) (*collector, *httptest.Server) {
+	t.Helper()
+
+	c := &collector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			body = zr
+		}
+		data, err := io.ReadAll(body)
+		assert.NoError(t, err)
+
+		c.mu.Lock()
+		c.requests = append(c.requests, r)
+		c.bodies = append(c.bodies, data)
+		n := len(c.requests)
+		c.mu.Unlock()
+
+		if status != nil {
+			w.WriteHeader(status(n))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func (c *collector) received() ([]*http.Request, [][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*http.Request(nil), c.requests...), append([][]byte(nil), c.bodies...)
+}
+
+// protoMessage is a decoded protobuf message: the values of the fields by their numbers.
+type protoMessage map[protowire.Number][]any
+
+func decodeProto(t *testing.T, b []byte) protoMessage {
+	t.Helper()
+
+	m := protoMessage{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		var value any
+		switch typ {
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			value, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		m[num] = append(m[num], value)
+	}
+	return m
+}
+
+func (m protoMessage) message(t *testing.T, num protowire.Number, i int) protoMessage {
+	t.Helper()
+
+	require.Greater(t, len(m[num]), i)
+	return decodeProto(t, m[num][i].([]byte))
+}
+
+// protoAttributes decodes the KeyValue fields to the map of the AnyValue messages.
+func protoAttributes(t *testing.T, m protoMessage, num protowire.Number) map[string]protoMessage {
+	t.Helper()
+
+	attrs := map[string]protoMessage{}
+	for i := range m[num] {
+		kv := m.message(t, num, i)
+		attrs[string(kv[1][0].([]byte))] = kv.message(t, 2, 0)
+	}
+	return attrs
+}
+
+func TestOTLPWriter_Protobuf(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewOTLPWriter(srv.URL, WithOTLPGzip(), WithOTLPHeader("Authorization", "Bearer token"),
+		WithOTLPResource("service.name", "cdn-edge"))
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutput(w))
+
+	// Act
+	l.Warn(context.Background(), "slow origin",
+		"trace_id", "0af7651916cd43dd8448eb211c80319c", "span_id", "b7ad6b7169203331",
+		"latency_ms", 1500, "ratio", 0.5, "cached", false, "origin", map[string]any{"host": "o1"})
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	requests, bodies := c.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "application/x-protobuf", requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", requests[0].Header.Get("Authorization"))
+
+	resourceLogs := decodeProto(t, bodies[0]).message(t, 1, 0)
+	resource := protoAttributes(t, resourceLogs.message(t, 1, 0), 1)
+	assert.Equal(t, "cdn-edge", string(resource["service.name"][1][0].([]byte)))
+
+	scopeLogs := resourceLogs.message(t, 2, 0)
+	assert.Equal(t, OTLPScopeName, string(scopeLogs.message(t, 1, 0)[1][0].([]byte)))
+	require.Len(t, scopeLogs[2], 1)
+
+	record := scopeLogs.message(t, 2, 0)
+	assert.NotZero(t, record[1][0])
+	assert.Equal(t, uint64(13), record[2][0])
+	assert.Equal(t, "WARN", string(record[3][0].([]byte)))
+	assert.Equal(t, "slow origin", string(record.message(t, 5, 0)[1][0].([]byte)))
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", hex.EncodeToString(record[9][0].([]byte)))
+	assert.Equal(t, "b7ad6b7169203331", hex.EncodeToString(record[10][0].([]byte)))
+	assert.NotZero(t, record[11][0])
+
+	attrs := protoAttributes(t, record, 6)
+	assert.Equal(t, uint64(1500), attrs["latency_ms"][3][0])
+	assert.Contains(t, attrs["ratio"], protowire.Number(4))
+	assert.Equal(t, uint64(0), attrs["cached"][2][0])
+	origin := protoAttributes(t, attrs["origin"].message(t, 6, 0), 1)
+	assert.Equal(t, "o1", string(origin["host"][1][0].([]byte)))
+	assert.NotContains(t, attrs, "trace_id")
+	assert.NotContains(t, attrs, "message")
+}
+
+func TestOTLPWriter_JSON(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewOTLPWriter(srv.URL, WithOTLPEncoding(OTLPJSON))
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutput(w))
+
+	// Act
+	l.Error(context.Background(), errors.New("boom"), "purge failed",
+		"trace_id", "0af7651916cd43dd8448eb211c80319c", "span_id", "b7ad6b7169203331", "objects", 3)
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	requests, bodies := c.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+
+	var req struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				Scope      struct{ Name string }
+				LogRecords []map[string]any
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(bodies[0], &req))
+	require.Len(t, req.ResourceLogs, 1)
+	require.Len(t, req.ResourceLogs[0].ScopeLogs, 1)
+	assert.Equal(t, OTLPScopeName, req.ResourceLogs[0].ScopeLogs[0].Scope.Name)
+	require.Len(t, req.ResourceLogs[0].ScopeLogs[0].LogRecords, 1)
+
+	record := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.IsType(t, "", record["timeUnixNano"])
+	assert.InDelta(t, 17, record["severityNumber"], 0)
+	assert.Equal(t, "ERROR", record["severityText"])
+	assert.Equal(t, map[string]any{"stringValue": "purge failed"}, record["body"])
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", record["traceId"])
+	assert.Equal(t, "b7ad6b7169203331", record["spanId"])
+	assert.ElementsMatch(t, []any{
+		map[string]any{"key": "exception.message", "value": map[string]any{"stringValue": "boom"}},
+		map[string]any{"key": "objects", "value": map[string]any{"intValue": "3"}},
+	}, record["attributes"])
+}
+
+func TestOTLPWriter_Batching(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, nil)
+	w := NewOTLPWriter(srv.URL, WithOTLPBatchSize(2), WithOTLPFlushInterval(time.Hour))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	for range 5 {
+		l.Info(context.Background(), "entry")
+	}
+	require.NoError(t, w.ForceFlush(context.Background()))
+
+	// Assert
+	_, bodies := c.received()
+	var records []int
+	for _, body := range bodies {
+		scopeLogs := decodeProto(t, body).message(t, 1, 0).message(t, 2, 0)
+		records = append(records, len(scopeLogs[2]))
+	}
+	assert.Equal(t, []int{2, 2, 1}, records)
+	require.NoError(t, w.Shutdown(context.Background()))
+}
+
+func TestOTLPWriter_Retry(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, func(n int) int {
+		if n < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	w := NewOTLPWriter(srv.URL, WithOTLPRetry(3, time.Millisecond, 10*time.Millisecond))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "retried")
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	requests, bodies := c.received()
+	assert.Len(t, requests, 3)
+	assert.Equal(t, bodies[0], bodies[2])
+	assert.Zero(t, w.Dropped())
+}
+
+func TestOTLPWriter_NotRetried(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, func(int) int { return http.StatusBadRequest })
+	var errs atomic.Int32
+	w := NewOTLPWriter(srv.URL, WithOTLPRetry(3, time.Millisecond, time.Millisecond),
+		WithOTLPErrorHandler(func(error) { errs.Add(1) }))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "rejected")
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	requests, _ := c.received()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, uint64(1), w.Dropped())
+	assert.Equal(t, int32(1), errs.Load())
+}
+
+func TestOTLPWriter_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	c, srv := newCollector(t, func(int) int { return http.StatusServiceUnavailable })
+	w := NewOTLPWriter(srv.URL, WithOTLPRetry(100, time.Hour, time.Hour), WithOTLPErrorHandler(func(error) {}))
+	l := NewLogger(DebugLevel, WithOutput(w))
+	l.Info(context.Background(), "pending")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := w.Shutdown(ctx)
+
+	// Assert
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	requests, _ := c.received()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, uint64(1), w.Dropped())
+	_, err = w.Write([]byte(`{"level":"INFO","message":"late"}`))
+	assert.ErrorIs(t, err, ErrOTLPShutdown)
+}
+
+func TestOTLPWriter_DroppedReport(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	unblock := make(chan struct{})
+	_, srv := newCollector(t, func(int) int {
+		<-unblock
+		return http.StatusOK
+	})
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	w := NewOTLPWriter(srv.URL, WithOTLPQueueSize(1), WithOTLPBatchSize(1), WithOTLPErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	for range 10 {
+		l.Info(context.Background(), "flood")
+	}
+	mu.Lock()
+	reported := len(errs)
+	mu.Unlock()
+	close(unblock)
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	// Assert
+	assert.Zero(t, reported)
+	require.NotZero(t, w.Dropped())
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], fmt.Sprintf("queue is full, %d log entries dropped", w.Dropped()))
+}
@@ -0,0 +1,352 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// otlpRecord is the LogRecord of the OpenTelemetry log data model.
+type otlpRecord struct {
+	timeUnixNano         uint64
+	observedTimeUnixNano uint64
+	severityNumber       int
+	severityText         string
+	body                 otlpValue
+	attributes           []otlpKeyValue
+	traceID              []byte
+	spanID               []byte
+}
+
+type otlpKeyValue struct {
+	key   string
+	value otlpValue
+}
+
+type otlpValueKind int8
+
+const (
+	otlpEmpty otlpValueKind = iota
+	otlpString
+	otlpBool
+	otlpInt
+	otlpDouble
+	otlpArray
+	otlpKeyValueList
+)
+
+// otlpValue is the AnyValue of the OpenTelemetry log data model.
+type otlpValue struct {
+	kind    otlpValueKind
+	str     string
+	boolean bool
+	integer int64
+	double  float64
+	values  []otlpValue
+	kvs     []otlpKeyValue
+}
+
+// newOTLPRecord converts the JSON entry to the log record with the keys mapped as ProfileOTel does.
+func newOTLPRecord(level zerolog.Level, p []byte, observed time.Time) (otlpRecord, error) {
+	fields, err := parseJSONFields(p)
+	if err != nil {
+		return otlpRecord{}, err
+	}
+
+	r := otlpRecord{observedTimeUnixNano: uint64(observed.UnixNano())} //nolint:gosec
+	for _, f := range otelFields(fields) {
+		switch f.key {
+		case "timestamp":
+			r.timeUnixNano = parseOTLPTime(f.raw)
+		case "severity_text":
+			_ = json.Unmarshal(f.raw, &r.severityText)
+		case "severity_number":
+			_ = json.Unmarshal(f.raw, &r.severityNumber)
+		case "body":
+			r.body = otlpValueFromJSON(f.raw)
+		case "trace_id":
+			r.traceID = parseOTLPID(f.raw, 16) //nolint:mnd
+		case "span_id":
+			r.spanID = parseOTLPID(f.raw, 8) //nolint:mnd
+		case "attributes":
+			r.attributes = otlpValueFromJSON(f.raw).kvs
+		}
+	}
+
+	if r.severityNumber == 0 && level != zerolog.NoLevel {
+		r.severityNumber = otelSeverity(level)
+		r.severityText = Level(level).String()
+	}
+	return r, nil
+}
+
+func parseOTLPTime(raw json.RawMessage) uint64 {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0
+	}
+	t, err := time.Parse(zerolog.TimeFieldFormat, s)
+	if err != nil {
+		return 0
+	}
+	return uint64(t.UnixNano()) //nolint:gosec
+}
+
+func parseOTLPID(raw json.RawMessage, size int) []byte {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil || len(id) != size {
+		return nil
+	}
+	return id
+}
+
+func otlpValueFromJSON(raw json.RawMessage) otlpValue {
+	if len(raw) == 0 {
+		return otlpValue{}
+	}
+
+	switch raw[0] {
+	case '"':
+		var s string
+		_ = json.Unmarshal(raw, &s)
+		return otlpValue{kind: otlpString, str: s}
+	case 't', 'f':
+		return otlpValue{kind: otlpBool, boolean: raw[0] == 't'}
+	case 'n':
+		return otlpValue{}
+	case '[':
+		var items []json.RawMessage
+		_ = json.Unmarshal(raw, &items)
+		v := otlpValue{kind: otlpArray, values: make([]otlpValue, 0, len(items))}
+		for _, item := range items {
+			v.values = append(v.values, otlpValueFromJSON(item))
+		}
+		return v
+	case '{':
+		fields, _ := parseJSONFields(raw)
+		v := otlpValue{kind: otlpKeyValueList, kvs: make([]otlpKeyValue, 0, len(fields))}
+		for _, f := range fields {
+			v.kvs = append(v.kvs, otlpKeyValue{key: f.key, value: otlpValueFromJSON(f.raw)})
+		}
+		return v
+	default:
+		if i, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return otlpValue{kind: otlpInt, integer: i}
+		}
+		d, _ := strconv.ParseFloat(string(raw), 64)
+		return otlpValue{kind: otlpDouble, double: d}
+	}
+}
+
+func otlpValueOf(v any) otlpValue {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return otlpValue{kind: otlpString, str: err.Error()}
+	}
+	return otlpValueFromJSON(data)
+}
+
+// Protobuf encoding of opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest.
+
+func appendOTLPRequestProto(b []byte, resource []otlpKeyValue, scope string, records []otlpRecord) []byte {
+	var res []byte
+	for _, kv := range resource {
+		res = appendProtoMessage(res, 1, appendOTLPKeyValueProto(nil, kv))
+	}
+
+	scopeLogs := appendProtoMessage(nil, 1, protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), scope))
+	for _, r := range records {
+		scopeLogs = appendProtoMessage(scopeLogs, 2, appendOTLPRecordProto(nil, r))
+	}
+
+	resourceLogs := appendProtoMessage(nil, 1, res)
+	resourceLogs = appendProtoMessage(resourceLogs, 2, scopeLogs)
+
+	return appendProtoMessage(b, 1, resourceLogs)
+}
+
+func appendOTLPRecordProto(b []byte, r otlpRecord) []byte {
+	if r.timeUnixNano != 0 {
+		b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, r.timeUnixNano)
+	}
+	if r.severityNumber != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(r.severityNumber)) //nolint:gosec
+	}
+	if r.severityText != "" {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, r.severityText)
+	}
+	if r.body.kind != otlpEmpty {
+		b = appendProtoMessage(b, 5, appendOTLPValueProto(nil, r.body))
+	}
+	for _, kv := range r.attributes {
+		b = appendProtoMessage(b, 6, appendOTLPKeyValueProto(nil, kv))
+	}
+	if len(r.traceID) > 0 {
+		b = protowire.AppendTag(b, 9, protowire.BytesType)
+		b = protowire.AppendBytes(b, r.traceID)
+	}
+	if len(r.spanID) > 0 {
+		b = protowire.AppendTag(b, 10, protowire.BytesType)
+		b = protowire.AppendBytes(b, r.spanID)
+	}
+	if r.observedTimeUnixNano != 0 {
+		b = protowire.AppendTag(b, 11, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, r.observedTimeUnixNano)
+	}
+	return b
+}
+
+func appendOTLPKeyValueProto(b []byte, kv otlpKeyValue) []byte {
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, kv.key)
+	return appendProtoMessage(b, 2, appendOTLPValueProto(nil, kv.value))
+}
+
+func appendOTLPValueProto(b []byte, v otlpValue) []byte {
+	switch v.kind {
+	case otlpString:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, v.str)
+	case otlpBool:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v.boolean))
+	case otlpInt:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v.integer)) //nolint:gosec
+	case otlpDouble:
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v.double))
+	case otlpArray:
+		var values []byte
+		for _, item := range v.values {
+			values = appendProtoMessage(values, 1, appendOTLPValueProto(nil, item))
+		}
+		b = appendProtoMessage(b, 5, values)
+	case otlpKeyValueList:
+		var values []byte
+		for _, kv := range v.kvs {
+			values = appendProtoMessage(values, 1, appendOTLPKeyValueProto(nil, kv))
+		}
+		b = appendProtoMessage(b, 6, values)
+	}
+	return b
+}
+
+func appendProtoMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// JSON encoding (OTLP/JSON): lowerCamelCase keys, 64-bit integers as strings, enums as numbers
+// and hex-encoded trace and span IDs.
+
+type otlpJSONRequest struct {
+	ResourceLogs []otlpJSONResourceLogs `json:"resourceLogs"`
+}
+
+type otlpJSONResourceLogs struct {
+	Resource  otlpJSONResource    `json:"resource"`
+	ScopeLogs []otlpJSONScopeLogs `json:"scopeLogs"`
+}
+
+type otlpJSONResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpJSONScopeLogs struct {
+	Scope      otlpJSONScope `json:"scope"`
+	LogRecords []otlpRecord  `json:"logRecords"`
+}
+
+type otlpJSONScope struct {
+	Name string `json:"name"`
+}
+
+func marshalOTLPRequestJSON(resource []otlpKeyValue, scope string, records []otlpRecord) ([]byte, error) {
+	return json.Marshal(otlpJSONRequest{ResourceLogs: []otlpJSONResourceLogs{{
+		Resource: otlpJSONResource{Attributes: resource},
+		ScopeLogs: []otlpJSONScopeLogs{{
+			Scope:      otlpJSONScope{Name: scope},
+			LogRecords: records,
+		}},
+	}}})
+}
+
+func (r otlpRecord) MarshalJSON() ([]byte, error) {
+	type record struct {
+		TimeUnixNano         string         `json:"timeUnixNano,omitempty"`
+		ObservedTimeUnixNano string         `json:"observedTimeUnixNano,omitempty"`
+		SeverityNumber       int            `json:"severityNumber,omitempty"`
+		SeverityText         string         `json:"severityText,omitempty"`
+		Body                 *otlpValue     `json:"body,omitempty"`
+		Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+		TraceID              string         `json:"traceId,omitempty"`
+		SpanID               string         `json:"spanId,omitempty"`
+	}
+
+	v := record{
+		SeverityNumber: r.severityNumber,
+		SeverityText:   r.severityText,
+		Attributes:     r.attributes,
+		TraceID:        hex.EncodeToString(r.traceID),
+		SpanID:         hex.EncodeToString(r.spanID),
+	}
+	if r.timeUnixNano != 0 {
+		v.TimeUnixNano = strconv.FormatUint(r.timeUnixNano, 10)
+	}
+	if r.observedTimeUnixNano != 0 {
+		v.ObservedTimeUnixNano = strconv.FormatUint(r.observedTimeUnixNano, 10)
+	}
+	if r.body.kind != otlpEmpty {
+		v.Body = &r.body
+	}
+	return json.Marshal(v)
+}
+
+func (kv otlpKeyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}{Key: kv.key, Value: kv.value})
+}
+
+func (v otlpValue) MarshalJSON() ([]byte, error) {
+	switch v.kind {
+	case otlpString:
+		return json.Marshal(map[string]string{"stringValue": v.str})
+	case otlpBool:
+		return json.Marshal(map[string]bool{"boolValue": v.boolean})
+	case otlpInt:
+		return json.Marshal(map[string]string{"intValue": strconv.FormatInt(v.integer, 10)})
+	case otlpDouble:
+		return json.Marshal(map[string]float64{"doubleValue": v.double})
+	case otlpArray:
+		values := v.values
+		if values == nil {
+			values = []otlpValue{}
+		}
+		return json.Marshal(map[string]any{"arrayValue": map[string]any{"values": values}})
+	case otlpKeyValueList:
+		kvs := v.kvs
+		if kvs == nil {
+			kvs = []otlpKeyValue{}
+		}
+		return json.Marshal(map[string]any{"kvlistValue": map[string]any{"values": kvs}})
+	default:
+		return []byte("{}"), nil
+	}
+}
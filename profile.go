@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// OutputProfile determines the key names and the level representation of the JSON entries.
+type OutputProfile int8
+
+const (
+	// ProfileECS is the Elastic Common Schema: "@timestamp", "log.level" (lower-case), "message",
+	// "error.message", "error.stack_trace", "log.origin.file.name", "log.origin.file.line", "log.logger",
+	// "trace.id", "span.id" and "ecs.version".
+	ProfileECS OutputProfile = iota + 1
+	// ProfileOTel is the OpenTelemetry log data model: "timestamp", "severity_text", "severity_number", "body",
+	// "trace_id", "span_id", and the rest of the fields in "attributes" ("exception.message",
+	// "exception.stacktrace", "code.filepath" and "code.lineno" for the error and the caller).
+	ProfileOTel
+)
+
+// ECSVersion is the version of the Elastic Common Schema written by ProfileECS.
+const ECSVersion = "8.11.0"
+
+// ProfileWriter remaps the keys and the levels of the JSON entries according to the profile.
+//
+// Unlike changing the zerolog.*FieldName variables, the profile affects only the loggers writing to the writer.
+type ProfileWriter struct {
+	profile OutputProfile
+	out     io.Writer
+}
+
+// NewProfileWriter creates a writer that writes the entries remapped according to the profile to w.
+func NewProfileWriter(profile OutputProfile, w io.Writer) *ProfileWriter {
+	return &ProfileWriter{profile: profile, out: w}
+}
+
+// WithOutputProfile creates an output writer with the keys and the levels remapped according to the profile,
+// see ProfileWriter.
+//
+// Optionally the inner output writer could be specified as second argument.
+// Otherwise, the os.Stdout will be used.
+func WithOutputProfile(profile OutputProfile, w ...io.Writer) Option {
+	if len(w) == 0 {
+		w = []io.Writer{os.Stdout}
+	}
+	return WithOutput(NewProfileWriter(profile, w[0]))
+}
+
+// WithGrpcOutputProfile is the GrpcOption counterpart of WithOutputProfile.
+func WithGrpcOutputProfile(profile OutputProfile, w ...io.Writer) GrpcOption {
+	if len(w) == 0 {
+		w = []io.Writer{os.Stdout}
+	}
+	return WithGrpcOutput(NewProfileWriter(profile, w[0]))
+}
+
+func (w *ProfileWriter) Write(p []byte) (int, error) {
+	line, err := w.format(p)
+	if err != nil {
+		// Not a JSON entry, write it as is.
+		return w.out.Write(p)
+	}
+	if _, err := w.out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteLevel passes the level to the inner writer if it implements zerolog.LevelWriter.
+func (w *ProfileWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	line, err := w.format(p)
+	if err != nil {
+		return writeLevel(w.out, level, p)
+	}
+	if _, err := writeLevel(w.out, level, line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *ProfileWriter) format(p []byte) ([]byte, error) {
+	fields, err := parseJSONFields(p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch w.profile {
+	case ProfileECS:
+		fields = ecsFields(fields)
+	case ProfileOTel:
+		fields = otelFields(fields)
+	}
+
+	buf := appendJSONObject(nil, fields)
+	return append(buf, '\n'), nil
+}
+
+func ecsFields(fields []jsonField) []jsonField {
+	result := make([]jsonField, 0, len(fields)+2) //nolint:mnd
+	for _, f := range fields {
+		switch f.key {
+		case zerolog.TimestampFieldName:
+			result = append(result, jsonField{key: "@timestamp", raw: f.raw})
+		case zerolog.LevelFieldName:
+			text, _ := levelText(f.raw)
+			result = append(result, jsonField{key: "log.level", raw: jsonString(strings.ToLower(text))})
+		case zerolog.MessageFieldName:
+			result = append(result, jsonField{key: "message", raw: f.raw})
+		case zerolog.ErrorFieldName:
+			result = append(result, jsonField{key: "error.message", raw: f.raw})
+		case zerolog.ErrorStackFieldName:
+			result = append(result, jsonField{key: "error.stack_trace", raw: stringValue(f.raw)})
+		case zerolog.CallerFieldName:
+			result = appendCaller(result, f.raw, "log.origin.file.name", "log.origin.file.line")
+		case LoggerFieldName:
+			result = append(result, jsonField{key: "log.logger", raw: f.raw})
+		case "trace_id":
+			result = append(result, jsonField{key: "trace.id", raw: f.raw})
+		case "span_id":
+			result = append(result, jsonField{key: "span.id", raw: f.raw})
+		default:
+			result = append(result, f)
+		}
+	}
+	return append(result, jsonField{key: "ecs.version", raw: jsonString(ECSVersion)})
+}
+
+func otelFields(fields []jsonField) []jsonField {
+	var result, attributes []jsonField
+	for _, f := range fields {
+		switch f.key {
+		case zerolog.TimestampFieldName:
+			result = append(result, jsonField{key: "timestamp", raw: f.raw})
+		case zerolog.LevelFieldName:
+			text, level := levelText(f.raw)
+			result = append(result,
+				jsonField{key: "severity_text", raw: jsonString(text)},
+				jsonField{key: "severity_number", raw: json.RawMessage(strconv.Itoa(otelSeverity(level)))},
+			)
+		case zerolog.MessageFieldName:
+			result = append(result, jsonField{key: "body", raw: f.raw})
+		case "trace_id", "span_id":
+			result = append(result, f)
+		case zerolog.ErrorFieldName:
+			attributes = append(attributes, jsonField{key: "exception.message", raw: f.raw})
+		case zerolog.ErrorStackFieldName:
+			attributes = append(attributes, jsonField{key: "exception.stacktrace", raw: stringValue(f.raw)})
+		case zerolog.CallerFieldName:
+			attributes = appendCaller(attributes, f.raw, "code.filepath", "code.lineno")
+		default:
+			attributes = append(attributes, f)
+		}
+	}
+	if len(attributes) > 0 {
+		result = append(result, jsonField{key: "attributes", raw: appendJSONObject(nil, attributes)})
+	}
+	return result
+}
+
+// levelText returns the level name in upper case and the parsed level.
+func levelText(raw json.RawMessage) (string, zerolog.Level) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return string(raw), zerolog.NoLevel
+	}
+	level, err := zerolog.ParseLevel(strings.ToLower(text))
+	if err != nil {
+		return strings.ToUpper(text), zerolog.NoLevel
+	}
+	return strings.ToUpper(text), level
+}
+
+// otelSeverity returns the severity number of the OpenTelemetry log data model.
+func otelSeverity(level zerolog.Level) int {
+	switch level {
+	case zerolog.TraceLevel:
+		return 1 //nolint:mnd
+	case zerolog.DebugLevel:
+		return 5 //nolint:mnd
+	case zerolog.InfoLevel:
+		return 9 //nolint:mnd
+	case zerolog.WarnLevel:
+		return 13 //nolint:mnd
+	case zerolog.ErrorLevel:
+		return 17 //nolint:mnd
+	case zerolog.FatalLevel:
+		return 21 //nolint:mnd
+	case zerolog.PanicLevel:
+		return 24 //nolint:mnd
+	default:
+		return 0
+	}
+}
+
+// appendCaller splits the "file:line" caller into the file and the line fields.
+func appendCaller(fields []jsonField, raw json.RawMessage, fileKey, lineKey string) []jsonField {
+	var caller string
+	if err := json.Unmarshal(raw, &caller); err != nil {
+		return append(fields, jsonField{key: fileKey, raw: raw})
+	}
+
+	if i := strings.LastIndexByte(caller, ':'); i >= 0 {
+		if line, err := strconv.Atoi(caller[i+1:]); err == nil {
+			return append(fields,
+				jsonField{key: fileKey, raw: jsonString(caller[:i])},
+				jsonField{key: lineKey, raw: json.RawMessage(strconv.Itoa(line))},
+			)
+		}
+	}
+	return append(fields, jsonField{key: fileKey, raw: raw})
+}
+
+// stringValue converts the nested value to the string with the compacted JSON.
+func stringValue(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 || raw[0] == '"' {
+		return raw
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return raw
+	}
+	return jsonString(compact.String())
+}
+
+func jsonString(s string) json.RawMessage {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+func appendJSONObject(dst []byte, fields []jsonField) []byte {
+	dst = append(dst, '{')
+	for i, f := range fields {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, jsonString(f.key)...)
+		dst = append(dst, ':')
+		dst = append(dst, f.raw...)
+	}
+	return append(dst, '}')
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOutputProfile_ECS(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	buf := &bytes.Buffer{}
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutputProfile(ProfileECS, buf)).Named("cache")
+
+	// Act
+	l.Error(context.Background(), errors.New("boom"), "purge failed", "trace_id", "abc", "key", "/img")
+
+	// Assert
+	entry := decodeEntry(t, buf.Bytes())
+	assert.Contains(t, entry, "@timestamp")
+	assert.Equal(t, "error", entry["log.level"])
+	assert.Equal(t, "purge failed", entry["message"])
+	assert.Equal(t, "boom", entry["error.message"])
+	assert.Equal(t, "cache", entry["log.logger"])
+	assert.Equal(t, "abc", entry["trace.id"])
+	assert.Equal(t, "/img", entry["key"])
+	assert.Equal(t, ECSVersion, entry["ecs.version"])
+	for _, key := range []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.ErrorFieldName, LoggerFieldName} {
+		assert.NotContains(t, entry, key)
+	}
+}
+
+func TestWithOutputProfile_OTel(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	buf := &bytes.Buffer{}
+	l := NewLogger(DebugLevel, WithTimestamp(), WithOutputProfile(ProfileOTel, buf))
+
+	// Act
+	l.Warn(context.Background(), "slow upstream", "span_id", "def", "upstream", "origin-1")
+
+	// Assert
+	entry := decodeEntry(t, buf.Bytes())
+	assert.Contains(t, entry, "timestamp")
+	assert.Equal(t, "WARN", entry["severity_text"])
+	assert.Equal(t, 13.0, entry["severity_number"])
+	assert.Equal(t, "slow upstream", entry["body"])
+	assert.Equal(t, "def", entry["span_id"])
+	assert.Equal(t, map[string]any{"upstream": "origin-1"}, entry["attributes"])
+}
+
+func TestWithOutputProfile_Caller(t *testing.T) {
+	SetCallerEnabled(true)
+	t.Cleanup(func() { SetCallerEnabled(false) })
+
+	// Arrange
+	ecs, otel := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := context.Background()
+
+	// Act
+	Info(ToContext(ctx, NewLogger(InfoLevel, WithOutputProfile(ProfileECS, ecs))), "ecs")
+	Error(ToContext(ctx, NewLogger(InfoLevel, WithOutputProfile(ProfileOTel, otel))), errors.New("boom"), "otel")
+
+	// Assert
+	ecsEntry := decodeEntry(t, ecs.Bytes())
+	assert.Contains(t, ecsEntry["log.origin.file.name"], "profile_test.go")
+	assert.Greater(t, ecsEntry["log.origin.file.line"], 0.0)
+
+	attributes, ok := decodeEntry(t, otel.Bytes())["attributes"].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, attributes["code.filepath"], "profile_test.go")
+	assert.Greater(t, attributes["code.lineno"], 0.0)
+	assert.Equal(t, "boom", attributes["exception.message"])
+}
+
+func TestWithOutputProfile_Isolation(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	plain, ecs := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := context.Background()
+
+	// Act
+	NewLogger(InfoLevel, WithOutputProfile(ProfileECS, ecs)).Info(ctx, "profiled")
+	NewLogger(InfoLevel, WithOutput(plain)).Info(ctx, "plain")
+
+	// Assert
+	// The profile doesn't change the keys of the other loggers.
+	entry := decodeEntry(t, plain.Bytes())
+	assert.Equal(t, InfoLevel.String(), entry[zerolog.LevelFieldName])
+	assert.Equal(t, "plain", entry[zerolog.MessageFieldName])
+	assert.NotContains(t, entry, "log.level")
+}
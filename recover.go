@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PanicFieldName is the field name for the recovered panic value.
+// The stack trace of the goroutine is added with the zerolog.ErrorStackFieldName field ("stack").
+const PanicFieldName = "panic"
+
+type recoveryConfig struct {
+	repanic bool
+}
+
+type RecoveryOption func(*recoveryConfig)
+
+// WithRecoveryRepanic panics again with the recovered value after the entry is written,
+// instead of returning the Internal status or writing the 500 response.
+func WithRecoveryRepanic() RecoveryOption {
+	return func(c *recoveryConfig) {
+		c.repanic = true
+	}
+}
+
+func newRecoveryConfig(opts []RecoveryOption) *recoveryConfig {
+	c := &recoveryConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Recover recovers from the panic and writes the ERROR entry with the panic value, the goroutine stack
+// and the context fields through the logger from the context (see FromContext).
+// The entry is never dropped by the sampling, and its caller is the function that panicked.
+//
+// Must be deferred directly:
+//
+//	defer log.Recover(ctx)
+func Recover(ctx context.Context) {
+	if r := recover(); r != nil {
+		logPanic(ctx, r)
+	}
+}
+
+// RecoverAndRepanic is the same as Recover, but panics again with the recovered value after the entry is written.
+//
+// Must be deferred directly:
+//
+//	defer log.RecoverAndRepanic(ctx)
+func RecoverAndRepanic(ctx context.Context) {
+	if r := recover(); r != nil {
+		logPanic(ctx, r)
+		panic(r)
+	}
+}
+
+// RecoveryUnaryServerInterceptor recovers from the panics in the handlers, writes the entries as Recover does
+// and returns the Internal status to the client.
+func RecoveryUnaryServerInterceptor(opts ...RecoveryOption) grpc.UnaryServerInterceptor {
+	c := newRecoveryConfig(opts)
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = c.grpcError(ctx, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamServerInterceptor is the stream counterpart of RecoveryUnaryServerInterceptor.
+func RecoveryStreamServerInterceptor(opts ...RecoveryOption) grpc.StreamServerInterceptor {
+	c := newRecoveryConfig(opts)
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = c.grpcError(ss.Context(), r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// RecoveryHandler recovers from the panics in the handler, writes the entries as Recover does
+// and responds with 500 Internal Server Error, if the response isn't started yet.
+//
+// The http.ErrAbortHandler panics are not logged and are passed through to abort the response.
+func RecoveryHandler(next http.Handler, opts ...RecoveryOption) http.Handler {
+	c := newRecoveryConfig(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(p)
+			}
+
+			logPanic(r.Context(), p)
+			if c.repanic {
+				panic(p)
+			}
+			if rw.status == 0 {
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func (c *recoveryConfig) grpcError(ctx context.Context, p any) error {
+	logPanic(ctx, p)
+	if c.repanic {
+		panic(p)
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+// logPanic writes the panic entry. Unlike the other entries, it bypasses the sampler,
+// and the caller is the function that panicked instead of the recovering one.
+func logPanic(ctx context.Context, p any) {
+	l := FromContext(ctx)
+	stack := panicStack()
+
+	Dump(ctx)
+	event := l.zerolog(ctx).Error()
+	if event == nil {
+		return
+	}
+	if callerEnabled.Load() && len(stack) > 0 {
+		frame, _ := runtime.CallersFrames(stack[:1]).Next()
+		event = event.Str(zerolog.CallerFieldName, zerolog.CallerMarshalFunc(frame.PC, frame.File, frame.Line))
+	}
+	if l.name != "" {
+		event = event.Str(LoggerFieldName, l.name)
+	}
+	event.Fields([]any(l.eventFields(ctx, Fields{
+		PanicFieldName, fmt.Sprint(p),
+		zerolog.ErrorStackFieldName, formatStack(stack),
+	}))).Msg("recovered from panic")
+}
+
+// panicStack returns the stack of the panicking goroutine starting from the function that panicked.
+func panicStack() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+
+	start := 0
+	for i, pc := range pcs {
+		if funcName(pc) == "runtime.gopanic" {
+			start = i + 1
+			// Skip the runtime frames of the runtime errors (e.g. runtime.sigpanic).
+			for start < len(pcs) && strings.HasPrefix(funcName(pcs[start]), "runtime.") {
+				start++
+			}
+			break
+		}
+	}
+	return pcs[start:]
+}
+
+func funcName(pc uintptr) string {
+	if f := runtime.FuncForPC(pc - 1); f != nil {
+		return f.Name()
+	}
+	return ""
+}
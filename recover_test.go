@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/cdnnow-pro/go-log/logtest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func panicking() {
+	panic("boom")
+}
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	t.Run("recover", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		rec := logtest.New()
+		ctx := InjectFields(rec.Context(context.Background(), InfoLevel), "job", "purge")
+
+		// Act
+		func() {
+			defer Recover(ctx)
+			panicking()
+		}()
+
+		// Assert
+		entries := rec.Entries()
+		require.Len(t, entries, 1)
+		assert.Equal(t, ErrorLevel, entries[0].Level)
+		assert.Equal(t, "recovered from panic", entries[0].Message)
+		assert.Equal(t, "boom", entries[0].Fields[PanicFieldName])
+		assert.Equal(t, "purge", entries[0].Fields["job"])
+
+		stack := stackOf(t, entries[0])
+		assert.Contains(t, stack[0]["func"], "panicking")
+	})
+
+	t.Run("repanic", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		rec := logtest.New()
+		ctx := rec.Context(context.Background(), InfoLevel)
+
+		// Act & Assert
+		assert.PanicsWithValue(t, "boom", func() {
+			defer RecoverAndRepanic(ctx)
+			panicking()
+		})
+		rec.AssertLogged(t, ErrorLevel, "recovered from panic", PanicFieldName, "boom")
+	})
+
+	t.Run("runtime error", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		rec := logtest.New()
+		ctx := rec.Context(context.Background(), InfoLevel)
+		var m map[string]int
+
+		// Act
+		func() {
+			defer Recover(ctx)
+			m["key"] = 1
+		}()
+
+		// Assert
+		entries := rec.Entries()
+		require.Len(t, entries, 1)
+		assert.Contains(t, entries[0].Fields[PanicFieldName], "nil map")
+		stack := stackOf(t, entries[0])
+		assert.NotContains(t, stack[0]["func"], "runtime.")
+		assert.Contains(t, entries[0].Fields, zerolog.ErrorStackFieldName)
+	})
+}
+
+func TestRecover_Sampling(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := rec.Context(context.Background(), InfoLevel, WithSampling(time.Hour, 1, 0))
+
+	// Act
+	for range 3 {
+		func() {
+			defer Recover(ctx)
+			panicking()
+		}()
+	}
+
+	// Assert
+	assert.Len(t, rec.Entries(), 3)
+}
+
+func TestRecover_Caller(t *testing.T) {
+	SetCallerEnabled(true)
+	t.Cleanup(func() { SetCallerEnabled(false) })
+
+	// Arrange
+	rec := logtest.New()
+	ctx := rec.Context(context.Background(), InfoLevel)
+
+	// Act
+	func() {
+		defer Recover(ctx)
+		panicking()
+	}()
+
+	// Assert
+	entries := rec.Entries()
+	require.Len(t, entries, 1)
+	// The caller is the panic in panicking, not the deferred Recover.
+	assert.True(t, strings.HasSuffix(entries[0].Caller, "recover_test.go:24"), entries[0].Caller)
+}
+
+func TestRecoveryUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := rec.Context(context.Background(), InfoLevel)
+	interceptor := RecoveryUnaryServerInterceptor()
+
+	// Act
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/cdn.Cache/Purge"},
+		func(context.Context, any) (any, error) {
+			panicking()
+			return "ok", nil
+		})
+
+	// Assert
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	rec.AssertLogged(t, ErrorLevel, "recovered from panic", PanicFieldName, "boom")
+}
+
+func TestRecoveryStreamServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	rec := logtest.New()
+	ctx := rec.Context(context.Background(), InfoLevel)
+	interceptor := RecoveryStreamServerInterceptor(WithRecoveryRepanic())
+
+	// Act & Assert
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = interceptor(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/cdn.Cache/Watch"},
+			func(any, grpc.ServerStream) error {
+				panicking()
+				return nil
+			})
+	})
+	rec.AssertLogged(t, ErrorLevel, "recovered from panic")
+}
+
+func TestRecoveryHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("internal server error", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		rec := logtest.New()
+		handler := RecoveryHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panicking()
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/purge", nil)
+		req = req.WithContext(rec.Context(req.Context(), InfoLevel))
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		rec.AssertLogged(t, ErrorLevel, "recovered from panic", PanicFieldName, "boom")
+	})
+
+	t.Run("response started", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		rec := logtest.New()
+		handler := RecoveryHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panicking()
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/purge", nil)
+		req = req.WithContext(rec.Context(req.Context(), InfoLevel))
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		rec.AssertLogged(t, ErrorLevel, "recovered from panic")
+	})
+
+	t.Run("abort handler", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		rec := logtest.New()
+		handler := RecoveryHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/purge", nil)
+		req = req.WithContext(rec.Context(req.Context(), InfoLevel))
+
+		// Act & Assert
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		})
+		assert.Empty(t, rec.Entries())
+	})
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
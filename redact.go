@@ -0,0 +1,428 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RedactAction determines what happens to the sensitive data.
+type RedactAction int8
+
+const (
+	// RedactMask replaces the data with RedactedValue.
+	RedactMask RedactAction = iota
+	// RedactHash replaces the data with its HMAC-SHA256, so the entries stay joinable by the value.
+	RedactHash
+	// RedactDrop removes the field (or the matched part of the message, or the query parameter).
+	RedactDrop
+)
+
+// RedactedValue replaces the masked data.
+const RedactedValue = "[REDACTED]"
+
+const redactHashPrefix = "hmac:"
+
+var (
+	// JWTPattern matches JSON Web Tokens.
+	JWTPattern = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	// BearerTokenPattern matches the credentials of the Bearer authorization scheme.
+	BearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`)
+	// CardNumberPattern matches the sequences of 13-19 digits, optionally separated by spaces or dashes.
+	// The matches are redacted only if they pass the Luhn check.
+	CardNumberPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+	queryParamPattern = regexp.MustCompile(`([?&])([^=&?#\s"']+)=([^&#\s"']*)`)
+)
+
+// Redactor removes the sensitive data from the fields, the messages and the errors.
+// It is configured on the logger with WithRedaction.
+type Redactor struct {
+	keys     []keyRule
+	patterns []patternRule
+	params   map[string]RedactAction
+	hashKey  []byte
+}
+
+type keyRule struct {
+	pattern string
+	action  RedactAction
+}
+
+type patternRule struct {
+	re     *regexp.Regexp
+	action RedactAction
+	valid  func(string) bool
+}
+
+type RedactOption func(*Redactor)
+
+// WithRedactedKeys redacts the values of the fields with the keys matching the patterns
+// (case-insensitive, in path.Match syntax, e.g. "authorization", "*_token").
+// The pattern is matched against both the whole key and its last dot-separated segment.
+func WithRedactedKeys(action RedactAction, patterns ...string) RedactOption {
+	return func(r *Redactor) {
+		for _, p := range patterns {
+			r.keys = append(r.keys, keyRule{pattern: strings.ToLower(p), action: action})
+		}
+	}
+}
+
+// WithRedactedPattern redacts the parts of the string values and the messages matching the regular expression.
+// RedactDrop removes the whole field if it's matched in a field value.
+func WithRedactedPattern(action RedactAction, re *regexp.Regexp) RedactOption {
+	return func(r *Redactor) {
+		rule := patternRule{re: re, action: action}
+		if re == CardNumberPattern {
+			rule.valid = luhnValid
+		}
+		r.patterns = append(r.patterns, rule)
+	}
+}
+
+// WithRedactedQueryParams redacts the values of the query parameters (case-insensitive)
+// of the URLs in the string values and the messages. RedactDrop removes the parameter.
+func WithRedactedQueryParams(action RedactAction, names ...string) RedactOption {
+	return func(r *Redactor) {
+		for _, name := range names {
+			r.params[strings.ToLower(name)] = action
+		}
+	}
+}
+
+// WithRedactHashKey sets the HMAC key for RedactHash. By default, a random key is generated,
+// so the hashes are joinable only within the process.
+func WithRedactHashKey(key []byte) RedactOption {
+	return func(r *Redactor) {
+		r.hashKey = key
+	}
+}
+
+// WithDefaultRedactionRules adds the rules for the common credentials: authorization headers, cookies,
+// passwords, tokens and secrets in the field keys, JWTs, bearer tokens and card numbers in the values,
+// and the signed URL query parameters.
+func WithDefaultRedactionRules() RedactOption {
+	return func(r *Redactor) {
+		for _, opt := range []RedactOption{
+			WithRedactedKeys(RedactMask,
+				"authorization", "proxy-authorization", "cookie", "set-cookie",
+				"password", "passwd", "secret", "*_secret", "token", "*_token", "api_key", "apikey",
+			),
+			WithRedactedPattern(RedactMask, JWTPattern),
+			WithRedactedPattern(RedactMask, BearerTokenPattern),
+			WithRedactedPattern(RedactMask, CardNumberPattern),
+			WithRedactedQueryParams(RedactMask,
+				"token", "access_token", "signature", "sig", "secret", "password",
+				"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+			),
+		} {
+			opt(r)
+		}
+	}
+}
+
+// NewRedactor creates a redactor with the specified rules.
+func NewRedactor(opts ...RedactOption) *Redactor {
+	r := &Redactor{params: map[string]RedactAction{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.hashKey == nil {
+		r.hashKey = make([]byte, 32) //nolint:mnd
+		_, _ = rand.Read(r.hashKey)
+	}
+	return r
+}
+
+// Fields returns the redacted copy of the fields. The original fields are not modified.
+func (r *Redactor) Fields(f Fields) Fields {
+	if r == nil || len(f) == 0 {
+		return f
+	}
+
+	result := make(Fields, 0, len(f)+len(f)%2)
+	i := f.Iterator()
+	for k, v, ok := i.Next(); ok; k, v, ok = i.Next() {
+		if action, ok := r.keyAction(k); ok {
+			if action != RedactDrop {
+				result = append(result, k, r.apply(action, fmt.Sprint(v)))
+			}
+			continue
+		}
+
+		if redacted, keep := r.fieldValue(v); keep {
+			result = append(result, k, redacted)
+		}
+	}
+	return result
+}
+
+// fieldValue redacts the field value. Returns false if the field must be dropped.
+//
+// The values of the types without the special handling are marshaled to JSON,
+// and the decoded tree is redacted as the nested fields. Such values are kept as is
+// if nothing is redacted, so their own marshaling (e.g. MarshalZerologObject) is used.
+func (r *Redactor) fieldValue(v any) (any, bool) {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64,
+		json.Number, time.Time, time.Duration:
+		return v, true
+	case string:
+		return r.value(v)
+	case []byte:
+		return r.stringValue(v, string(v))
+	case error:
+		return r.value(v.Error())
+	case *url.URL:
+		if v == nil {
+			return v, true
+		}
+		return r.value(v.Redacted())
+	case url.URL:
+		return r.value(v.Redacted())
+	case http.Header:
+		return http.Header(r.header(v)), true
+	case map[string][]string:
+		return r.header(v), true
+	case []string:
+		return r.stringSlice(v), true
+	case map[string]any:
+		return r.group(v), true
+	case []any:
+		return r.slice(v), true
+	case fmt.Stringer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return v, true
+		}
+		return r.value(v.String())
+	default:
+		return r.jsonValue(v)
+	}
+}
+
+// header redacts the header values, the header names are matched against the key rules.
+func (r *Redactor) header(h map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(h))
+	for name, values := range h {
+		action, ok := r.keyAction(name)
+		if ok && action == RedactDrop {
+			continue
+		}
+
+		redacted := make([]string, 0, len(values))
+		for _, s := range values {
+			if ok {
+				redacted = append(redacted, r.apply(action, s))
+			} else if s, keep := r.value(s); keep {
+				redacted = append(redacted, s)
+			}
+		}
+		result[name] = redacted
+	}
+	return result
+}
+
+// stringSlice redacts the string values (e.g. the command line arguments or the environment).
+// The "key=value" values, optionally prefixed with dashes, are matched against the key rules.
+func (r *Redactor) stringSlice(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, s := range values {
+		if i := strings.IndexByte(s, '='); i > 0 {
+			if action, ok := r.keyAction(strings.TrimLeft(s[:i], "-")); ok {
+				if action != RedactDrop {
+					result = append(result, s[:i+1]+r.apply(action, s[i+1:]))
+				}
+				continue
+			}
+		}
+		if s, keep := r.value(s); keep {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (r *Redactor) slice(values []any) []any {
+	result := make([]any, 0, len(values))
+	for _, v := range values {
+		if redacted, keep := r.fieldValue(v); keep {
+			result = append(result, redacted)
+		}
+	}
+	return result
+}
+
+// jsonValue redacts the JSON representation of the value.
+func (r *Redactor) jsonValue(v any) (any, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return r.stringValue(v, fmt.Sprint(v))
+	}
+
+	var tree any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return r.stringValue(v, fmt.Sprint(v))
+	}
+
+	redacted, keep := r.fieldValue(tree)
+	if keep && reflect.DeepEqual(redacted, tree) {
+		return v, true
+	}
+	return redacted, keep
+}
+
+// stringValue redacts the string representation of the value, the value is kept as is if nothing is redacted.
+func (r *Redactor) stringValue(v any, s string) (any, bool) {
+	redacted, keep := r.value(s)
+	if keep && redacted == s {
+		return v, true
+	}
+	return redacted, keep
+}
+
+// group redacts the nested fields (e.g. slog groups).
+func (r *Redactor) group(m map[string]any) map[string]any {
+	f := make(Fields, 0, len(m)*2) //nolint:mnd
+	for k, v := range m {
+		f = append(f, k, v)
+	}
+
+	redacted := r.Fields(f)
+	result := make(map[string]any, len(redacted)/2) //nolint:mnd
+	i := redacted.Iterator()
+	for k, v, ok := i.Next(); ok; k, v, ok = i.Next() {
+		result[k] = v
+	}
+	return result
+}
+
+// String returns the redacted string (e.g. a message or an error text).
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, p := range r.patterns {
+		s = r.replacePattern(p, s)
+	}
+	return r.replaceParams(s)
+}
+
+func (r *Redactor) keyAction(key string) (RedactAction, bool) {
+	key = strings.ToLower(key)
+	last := key
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		last = key[i+1:]
+	}
+
+	for _, rule := range r.keys {
+		if ok, _ := path.Match(rule.pattern, key); ok {
+			return rule.action, true
+		}
+		if ok, _ := path.Match(rule.pattern, last); ok {
+			return rule.action, true
+		}
+	}
+	return 0, false
+}
+
+// value redacts the string field value. Returns false if the field must be dropped.
+func (r *Redactor) value(s string) (string, bool) {
+	for _, p := range r.patterns {
+		if p.action == RedactDrop && r.matches(p, s) {
+			return "", false
+		}
+		s = r.replacePattern(p, s)
+	}
+	return r.replaceParams(s), true
+}
+
+func (r *Redactor) matches(p patternRule, s string) bool {
+	for _, m := range p.re.FindAllString(s, -1) {
+		if p.valid == nil || p.valid(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) replacePattern(p patternRule, s string) string {
+	return p.re.ReplaceAllStringFunc(s, func(m string) string {
+		if p.valid != nil && !p.valid(m) {
+			return m
+		}
+		return r.apply(p.action, m)
+	})
+}
+
+func (r *Redactor) replaceParams(s string) string {
+	if len(r.params) == 0 || !strings.ContainsAny(s, "?&") {
+		return s
+	}
+
+	return queryParamPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := queryParamPattern.FindStringSubmatch(m)
+		sep, name, value := sub[1], sub[2], sub[3]
+
+		action, ok := r.params[strings.ToLower(name)]
+		if !ok {
+			return m
+		}
+		if action == RedactDrop {
+			if sep == "?" {
+				// Keep the separator for the following parameters.
+				return sep
+			}
+			return ""
+		}
+		return sep + name + "=" + r.apply(action, value)
+	})
+}
+
+func (r *Redactor) apply(action RedactAction, s string) string {
+	switch action {
+	case RedactHash:
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		return redactHashPrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+	case RedactDrop:
+		return ""
+	default:
+		return RedactedValue
+	}
+}
+
+// luhnValid reports whether the digits in the string pass the Luhn check.
+func luhnValid(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"github.com/rs/zerolog"
+)
+
+// RouteFormat is the output format of a route.
+type RouteFormat int8
+
+const (
+	// RouteJSON writes the entries as JSON.
+	RouteJSON RouteFormat = iota
+	// RoutePlainText writes the entries in the plain text format, as WithPlainText does.
+	RoutePlainText
+	// RouteLogfmt writes the entries in the logfmt format, see LogfmtWriter.
+	RouteLogfmt
+)
+
+// Route is an output of RouteWriter for the entries in the level range, see NewRoute.
+type Route struct {
+	minLevel   Level
+	belowLevel Level
+	writer     io.Writer
+	format     RouteFormat
+}
+
+// RouteOption configures the route created with NewRoute.
+type RouteOption func(*Route)
+
+// WithRouteMinLevel sets the lowest level of the entries written to the route (all the levels by default).
+func WithRouteMinLevel(level Level) RouteOption {
+	return func(r *Route) {
+		r.minLevel = level
+	}
+}
+
+// WithRouteBelowLevel sets the level the entries must be below to be written to the route (no upper bound by default).
+// For example, WithRouteBelowLevel(DebugLevel) routes only the TRACE entries.
+func WithRouteBelowLevel(level Level) RouteOption {
+	return func(r *Route) {
+		r.belowLevel = level
+	}
+}
+
+// WithRouteFormat sets the format of the route (RouteJSON by default).
+func WithRouteFormat(format RouteFormat) RouteOption {
+	return func(r *Route) {
+		r.format = format
+	}
+}
+
+// NewRoute creates a route to the writer for the entries of all the levels, unless limited with the options.
+func NewRoute(w io.Writer, opts ...RouteOption) Route {
+	r := Route{
+		minLevel:   math.MinInt8,
+		belowLevel: math.MaxInt8,
+		writer:     w,
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// RouteWriter is a zerolog.LevelWriter that writes every entry to the routes matching its level.
+//
+// Unlike zerolog.MultiLevelWriter, each route gets only the entries of its level range, in its own format.
+// The entries without a level are written to the routes including InfoLevel.
+type RouteWriter struct {
+	routes []Route
+}
+
+// NewRouteWriter creates a writer to the routes. The routes without a writer are ignored.
+func NewRouteWriter(routes ...Route) *RouteWriter {
+	w := &RouteWriter{routes: make([]Route, 0, len(routes))}
+	for _, route := range routes {
+		if route.writer == nil {
+			continue
+		}
+		switch route.format {
+		case RoutePlainText:
+			route.writer = newPlainTextWriter(route.writer)
+		case RouteLogfmt:
+			route.writer = NewLogfmtWriter(route.writer)
+		}
+		w.routes = append(w.routes, route)
+	}
+	return w
+}
+
+// WithRoutes creates an output writer to the routes by level, see RouteWriter.
+//
+// For example, to write everything to stdout and the errors to a file as well:
+//
+//	log.WithRoutes(
+//		log.NewRoute(os.Stdout),
+//		log.NewRoute(file, log.WithRouteMinLevel(log.ErrorLevel)),
+//	)
+func WithRoutes(routes ...Route) Option {
+	return WithOutput(NewRouteWriter(routes...))
+}
+
+// WithGrpcRoutes creates an output writer to the routes by level, see RouteWriter.
+func WithGrpcRoutes(routes ...Route) GrpcOption {
+	return WithGrpcOutput(NewRouteWriter(routes...))
+}
+
+// Write writes the entry to the routes including InfoLevel.
+func (w *RouteWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel writes the entry to the routes matching the level.
+// All the matching routes are written even if some of them fail, and the errors are joined.
+func (w *RouteWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	routeLevel := Level(level)
+	if level == zerolog.NoLevel {
+		routeLevel = InfoLevel
+	}
+
+	var errs []error
+	for _, route := range w.routes {
+		if !route.matches(routeLevel) {
+			continue
+		}
+		if _, err := writeLevel(route.writer, level, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(p), errors.Join(errs...)
+}
+
+func (r Route) matches(level Level) bool {
+	return level >= r.minLevel && level < r.belowLevel
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRoutes(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	var all, text, errs bytes.Buffer
+	l := NewLogger(DebugLevel, WithRoutes(
+		NewRoute(&all),
+		NewRoute(&text, WithRouteMinLevel(InfoLevel), WithRouteBelowLevel(ErrorLevel), WithRouteFormat(RoutePlainText)),
+		NewRoute(&errs, WithRouteMinLevel(ErrorLevel), WithRouteFormat(RouteLogfmt)),
+	))
+
+	// Act
+	l.Debug(context.Background(), "debugged")
+	l.Info(context.Background(), "started", "port", 8080)
+	l.Warn(context.Background(), "slow")
+	l.Error(context.Background(), errors.New("boom"), "failed")
+
+	// Assert
+	lines := strings.Split(strings.TrimSpace(all.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.JSONEq(t, `{"level":"DEBUG","message":"debugged"}`, lines[0])
+	assert.JSONEq(t, `{"level":"ERROR","error":"boom","message":"failed"}`, lines[3])
+
+	lines = strings.Split(strings.TrimSpace(text.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "started")
+	assert.Contains(t, lines[0], "port=")
+	assert.Contains(t, lines[1], "slow")
+	assert.NotContains(t, text.String(), `"message"`)
+
+	assert.Equal(t, "level=ERROR msg=failed error=boom\n", errs.String())
+}
+
+func TestRouteWriter_NoLevel(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	var info, errs bytes.Buffer
+	w := NewRouteWriter(
+		NewRoute(&info, WithRouteMinLevel(InfoLevel), WithRouteBelowLevel(WarnLevel)),
+		NewRoute(&errs, WithRouteMinLevel(ErrorLevel)),
+	)
+
+	// Act
+	n, err := w.Write([]byte(`{"message":"plain"}` + "\n"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, len(`{"message":"plain"}`+"\n"), n)
+	assert.Equal(t, `{"message":"plain"}`+"\n", info.String())
+	assert.Empty(t, errs.String())
+}
+
+func TestRouteWriter_Trace(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	var all, trace, debug bytes.Buffer
+	w := NewRouteWriter(
+		NewRoute(&all),
+		NewRoute(&trace, WithRouteBelowLevel(DebugLevel)),
+		NewRoute(&debug, WithRouteMinLevel(DebugLevel)),
+	)
+
+	// Act
+	_, err := w.WriteLevel(zerolog.TraceLevel, []byte(`{"level":"TRACE","message":"traced"}`+"\n"))
+	require.NoError(t, err)
+	_, err = w.WriteLevel(zerolog.DebugLevel, []byte(`{"level":"DEBUG","message":"debugged"}`+"\n"))
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, `{"level":"TRACE","message":"traced"}`+"\n"+`{"level":"DEBUG","message":"debugged"}`+"\n", all.String())
+	assert.Equal(t, `{"level":"TRACE","message":"traced"}`+"\n", trace.String())
+	assert.Equal(t, `{"level":"DEBUG","message":"debugged"}`+"\n", debug.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRouteWriter_Error(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	var out bytes.Buffer
+	w := NewRouteWriter(NewRoute(failingWriter{}), NewRoute(&out))
+
+	// Act
+	_, err := w.Write([]byte(`{"level":"INFO","message":"written"}` + "\n"))
+
+	// Assert
+	require.EqualError(t, err, "disk full")
+	assert.Contains(t, out.String(), "written")
+}
@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Field names of the summary entries written by the sampler.
+const (
+	SuppressedFieldName     = "suppressed"
+	SampledMessageFieldName = "sampled_message"
+)
+
+// defaultSamplingInterval is the summary window when only the rate limit is set.
+const defaultSamplingInterval = time.Second
+
+// sampler limits the entries with the same level and message, see WithSampling and WithSamplingRateLimit.
+// It is shared by the logger and its children.
+type sampler struct {
+	interval   time.Duration
+	first      uint64
+	thereafter uint64
+	rate       float64
+	burst      float64
+	bypass     Level
+
+	mu          sync.Mutex
+	windowStart time.Time
+	timer       *time.Timer
+	counters    map[sampleKey]*sampleCounter
+}
+
+type sampleKey struct {
+	level Level
+	msg   string
+}
+
+type sampleCounter struct {
+	count      uint64
+	suppressed uint64
+	tokens     float64
+	refilled   time.Time
+}
+
+type sampleSummary struct {
+	key        sampleKey
+	suppressed uint64
+}
+
+// WithSampling limits the entries with the same level and message: the first entries
+// within the interval are written, then every thereafter-th entry is written (none if thereafter is 0).
+//
+// The sampled-out entries are counted, and the summary entry "suppressed N similar messages"
+// with the same level is written when the interval closes.
+// Force and the entries at the level set with WithSamplingBypass are never sampled.
+func WithSampling(interval time.Duration, first, thereafter int) Option {
+	return func(l *Logger) {
+		s := l.ensureSampler()
+		s.interval = interval
+		s.first = uint64(max(first, 0))
+		s.thereafter = uint64(max(thereafter, 0))
+	}
+}
+
+// WithSamplingRateLimit limits the entries with the same level and message with the token bucket
+// of the specified rate (entries per second) and burst.
+//
+// The rate limit is applied to the entries passed by WithSampling, if it's set.
+// Otherwise, the summary entries are written every second.
+func WithSamplingRateLimit(rate float64, burst int) Option {
+	return func(l *Logger) {
+		s := l.ensureSampler()
+		s.rate = rate
+		s.burst = float64(max(burst, 1))
+	}
+}
+
+// WithSamplingBypass disables the sampling for the entries with the specified level and above
+// (e.g. ErrorLevel to never drop the errors). Only fatal entries bypass the sampling by default.
+func WithSamplingBypass(level Level) Option {
+	return func(l *Logger) {
+		l.ensureSampler().bypass = level
+	}
+}
+
+func (l *Logger) ensureSampler() *sampler {
+	if l.sampler == nil {
+		l.sampler = &sampler{
+			bypass:   FatalLevel,
+			counters: map[sampleKey]*sampleCounter{},
+		}
+	}
+	return l.sampler
+}
+
+// sampled reports whether the entry passes the sampling.
+func (l *Logger) sampled(ctx context.Context, level Level, msg string) bool {
+	if l.sampler == nil || level < l.levelFor(ctx) || level >= l.sampler.bypass {
+		return true
+	}
+
+	ok, summaries := l.sampler.sample(l, level, msg, time.Now())
+	l.writeSummaries(summaries)
+	return ok
+}
+
+func (s *sampler) window() time.Duration {
+	if s.interval > 0 {
+		return s.interval
+	}
+	return defaultSamplingInterval
+}
+
+func (s *sampler) sample(l *Logger, level Level, msg string, now time.Time) (bool, []sampleSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var summaries []sampleSummary
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+	} else if now.Sub(s.windowStart) >= s.window() {
+		summaries = s.closeWindow(now)
+	}
+
+	key := sampleKey{level: level, msg: msg}
+	c, ok := s.counters[key]
+	if !ok {
+		c = &sampleCounter{tokens: s.burst, refilled: now}
+		s.counters[key] = c
+	}
+
+	if s.allow(c, now) {
+		return true, summaries
+	}
+
+	c.suppressed++
+	if s.timer == nil {
+		// Write the summary even if no entries follow the burst.
+		s.timer = time.AfterFunc(s.window()-now.Sub(s.windowStart), func() {
+			l.writeSummaries(s.flush(time.Now()))
+		})
+	}
+	return false, summaries
+}
+
+func (s *sampler) allow(c *sampleCounter, now time.Time) bool {
+	c.count++
+	if s.interval > 0 && c.count > s.first {
+		if s.thereafter == 0 || (c.count-s.first)%s.thereafter != 0 {
+			return false
+		}
+	}
+
+	if s.rate > 0 {
+		c.tokens = min(s.burst, c.tokens+now.Sub(c.refilled).Seconds()*s.rate)
+		c.refilled = now
+		if c.tokens < 1 {
+			return false
+		}
+		c.tokens--
+	}
+	return true
+}
+
+func (s *sampler) flush(now time.Time) []sampleSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.timer = nil
+	if now.Sub(s.windowStart) < s.window() {
+		return nil
+	}
+	return s.closeWindow(now)
+}
+
+// closeWindow resets the counters and returns the summaries of the closed window.
+// The counters of the keys without entries in the closed window are removed.
+func (s *sampler) closeWindow(now time.Time) []sampleSummary {
+	var summaries []sampleSummary
+	for key, c := range s.counters {
+		if c.suppressed > 0 {
+			summaries = append(summaries, sampleSummary{key: key, suppressed: c.suppressed})
+		}
+		if c.count == 0 {
+			delete(s.counters, key)
+			continue
+		}
+		c.count, c.suppressed = 0, 0
+	}
+
+	s.windowStart = now
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	return summaries
+}
+
+func (l *Logger) writeSummaries(summaries []sampleSummary) {
+	for _, sum := range summaries {
+		l.base().WithLevel(zerolog.Level(sum.key.level)).
+			Uint64(SuppressedFieldName, sum.suppressed).
+			Str(SampledMessageFieldName, l.redactor.String(sum.key.msg)).
+			Msg(fmt.Sprintf("suppressed %d similar messages", sum.suppressed))
+	}
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/cdnnow-pro/go-log/logtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampling_FirstThereafter(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel, WithSampling(time.Hour, 3, 5))
+
+	// Act
+	for range 20 {
+		l.Warn(ctx, "cache miss")
+	}
+	l.Warn(ctx, "other")
+	l.Info(ctx, "cache miss")
+
+	// Assert
+	// The first 3 entries and then the 5th, the 10th and the 15th of the remaining 17.
+	assert.Len(t, rec.Entries().FilterMessage("cache miss").FilterLevel(WarnLevel), 6)
+	rec.AssertLogged(t, WarnLevel, "other")
+	rec.AssertLogged(t, InfoLevel, "cache miss")
+}
+
+func TestSampling_Summary(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel, WithSampling(50*time.Millisecond, 2, 0))
+
+	// Act
+	for range 10 {
+		l.Warn(ctx, "cache miss", "key", "a")
+	}
+
+	// Assert
+	assert.Len(t, rec.Entries().FilterMessage("cache miss"), 2)
+	require.Eventually(t, func() bool {
+		return len(rec.Entries().FilterMessage("suppressed 8 similar messages")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	summary := rec.Entries().FilterMessage("suppressed 8 similar messages")[0]
+	assert.Equal(t, WarnLevel, summary.Level)
+	assert.Equal(t, 8.0, summary.Fields[SuppressedFieldName])
+	assert.Equal(t, "cache miss", summary.Fields[SampledMessageFieldName])
+
+	// The new window starts with the first entries written again.
+	l.Warn(ctx, "cache miss")
+	assert.Len(t, rec.Entries().FilterMessage("cache miss"), 3)
+}
+
+func TestSampling_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel, WithSamplingRateLimit(0.001, 4))
+
+	// Act
+	for range 10 {
+		l.Info(ctx, "request")
+	}
+
+	// Assert
+	assert.Len(t, rec.Entries().FilterMessage("request"), 4)
+	require.Eventually(t, func() bool {
+		return len(rec.Entries().FilterMessage("suppressed 6 similar messages")) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestSampling_Bypass(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel, WithSampling(time.Hour, 1, 0), WithSamplingBypass(ErrorLevel))
+	err := errors.New("boom")
+
+	// Act
+	for range 5 {
+		l.Force(ctx, "forced")
+		l.Error(ctx, err, "failed")
+		l.Warn(ctx, "sampled")
+	}
+
+	// Assert
+	assert.Len(t, rec.Entries().FilterMessage("forced"), 5)
+	assert.Len(t, rec.Entries().FilterMessage("failed"), 5)
+	assert.Len(t, rec.Entries().FilterMessage("sampled"), 1)
+}
+
+func TestSampling_DisabledLevel(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ctx := context.Background()
+	rec := logtest.New()
+	l := rec.Logger(InfoLevel, WithSampling(time.Hour, 1, 0))
+
+	// Act
+	for range 5 {
+		l.Debug(ctx, "hidden")
+	}
+	l.Level(DebugLevel).Debug(ctx, "hidden")
+
+	// Assert
+	// The entries below the level aren't counted by the sampler.
+	assert.Len(t, rec.Entries().FilterMessage("hidden"), 1)
+}
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: MIT
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"runtime"
+	"slices"
+
+	"github.com/rs/zerolog"
+)
+
+// slogHandler implements slog.Handler on top of Logger, so the records share the same
+// shape (level names, time format, context fields) as the ones produced by Logger methods.
+type slogHandler struct {
+	l *Logger
+
+	// frames holds the attributes and groups added with WithAttrs and WithGroup in order.
+	frames []slogFrame
+}
+
+type slogFrame struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewSlogHandler returns a slog.Handler that writes records through the specified logger.
+//
+// Fields injected to the context with InjectFields are added to every record.
+func NewSlogHandler(logger *Logger) slog.Handler {
+	return &slogHandler{l: logger}
+}
+
+// Enabled reports whether the level is enabled, or the record would be kept by the flight recorder
+// of the context (see WithFlightRecorder).
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return levelFromSlog(level) >= h.l.levelFor(ctx) || h.l.recorderFor(ctx, levelFromSlog(level)) != nil
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := levelFromSlog(r.Level)
+
+	var caller string
+	if callerEnabled.Load() && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		caller = zerolog.CallerMarshalFunc(frame.PC, frame.File, frame.Line)
+	}
+
+	if recorder := h.l.recorderFor(ctx, level); recorder != nil {
+		h.l.buffer(ctx, recorder, level, caller, r.Message, h.fields(r))
+		return nil
+	}
+	if !h.l.sampled(ctx, level, r.Message) {
+		return nil
+	}
+	if level >= ErrorLevel {
+		Dump(ctx)
+	}
+
+	event := h.l.zerolog(ctx).WithLevel(zerolog.Level(level))
+	if event == nil {
+		return nil
+	}
+
+	if caller != "" {
+		event = event.Str(zerolog.CallerFieldName, caller)
+	}
+	if h.l.name != "" {
+		event = event.Str(LoggerFieldName, h.l.name)
+	}
+
+	event.Fields([]any(h.l.eventFields(ctx, h.fields(r)))).Msg(h.l.redactor.String(r.Message))
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.withFrame(slogFrame{attrs: attrs})
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.withFrame(slogFrame{group: name})
+}
+
+func (h *slogHandler) withFrame(f slogFrame) *slogHandler {
+	frames := make([]slogFrame, len(h.frames), len(h.frames)+1)
+	copy(frames, h.frames)
+	return &slogHandler{l: h.l, frames: append(frames, f)}
+}
+
+// fields builds the record fields: top-level attributes are kept in order,
+// groups are rendered as nested objects. The groups without attributes are omitted.
+func (h *slogHandler) fields(r slog.Record) Fields {
+	var (
+		top     Fields
+		current map[string]any
+		// pending holds the groups opened with WithGroup and not yet added, since they may stay empty.
+		pending []string
+	)
+	add := func(a slog.Attr) {
+		k, v, ok := slogAttrValue(a)
+		if !ok {
+			return
+		}
+		for _, name := range pending {
+			group := map[string]any{}
+			if current == nil {
+				top = append(top, name, group)
+			} else {
+				current[name] = group
+			}
+			current = group
+		}
+		pending = pending[:0]
+
+		if current == nil {
+			top = appendSlogField(top, k, v)
+			return
+		}
+		mergeSlogField(current, k, v)
+	}
+
+	for _, f := range h.frames {
+		if f.group != "" {
+			pending = append(pending, f.group)
+			continue
+		}
+		for _, a := range f.attrs {
+			add(a)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		add(a)
+		return true
+	})
+
+	return top
+}
+
+// appendSlogField appends the field to f. Inlined groups (group attributes with empty key)
+// are flattened into f.
+func appendSlogField(f Fields, k string, v any) Fields {
+	if k != "" {
+		return append(f, k, v)
+	}
+	if group, ok := v.(map[string]any); ok {
+		for _, gk := range slices.Sorted(maps.Keys(group)) {
+			f = append(f, gk, group[gk])
+		}
+	}
+	return f
+}
+
+func slogAttrValue(a slog.Attr) (string, any, bool) {
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		if a.Key == "" {
+			return "", nil, false
+		}
+		return a.Key, v.Any(), true
+	}
+
+	attrs := v.Group()
+	if len(attrs) == 0 {
+		return "", nil, false
+	}
+
+	group := make(map[string]any, len(attrs))
+	for _, ga := range attrs {
+		if k, gv, ok := slogAttrValue(ga); ok {
+			mergeSlogField(group, k, gv)
+		}
+	}
+	if len(group) == 0 {
+		return "", nil, false
+	}
+	return a.Key, group, true
+}
+
+// mergeSlogField sets the field in the group. Inlined groups are merged into the group.
+func mergeSlogField(group map[string]any, k string, v any) {
+	if k != "" {
+		group[k] = v
+		return
+	}
+	if inline, ok := v.(map[string]any); ok {
+		for ik, iv := range inline {
+			group[ik] = iv
+		}
+	}
+}
+
+// levelFromSlog maps slog levels to Level: anything below slog.LevelDebug becomes TraceLevel
+// and anything above slog.LevelError is clamped to ErrorLevel, so a slog record never exits the process.
+func levelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
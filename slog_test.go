@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSlog(level Level) (*slog.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l := NewLogger(level, WithTimestamp(), WithOutput(buf))
+	return slog.New(NewSlogHandler(l)), buf
+}
+
+func TestSlogHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("levels", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			level slog.Level
+			want  Level
+		}{
+			{slog.LevelDebug - 4, TraceLevel},
+			{slog.LevelDebug, DebugLevel},
+			{slog.LevelInfo, InfoLevel},
+			{slog.LevelWarn, WarnLevel},
+			{slog.LevelError, ErrorLevel},
+			{slog.LevelError + 4, ErrorLevel},
+		}
+		for _, tt := range tests {
+			// Arrange
+			l, buf := setupSlog(TraceLevel)
+
+			// Act
+			l.Log(context.Background(), tt.level, "msg")
+
+			// Assert
+			entry := decodeEntry(t, buf.Bytes())
+			assert.Equal(t, tt.want.String(), entry[zerolog.LevelFieldName])
+		}
+	})
+
+	t.Run("same output as logger", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		l, buf := setupSlog(DebugLevel)
+
+		// Act
+		l.Info("infoMsg", "with_field_1", "test")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, InfoLevel.String(), entry[zerolog.LevelFieldName])
+		assert.Equal(t, "infoMsg", entry[zerolog.MessageFieldName])
+		assert.Equal(t, "test", entry["with_field_1"])
+		assert.NotEmpty(t, entry[zerolog.TimestampFieldName])
+	})
+
+	t.Run("disabled level", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		l, buf := setupSlog(WarnLevel)
+
+		// Act
+		l.Info("hidden")
+
+		// Assert
+		assert.False(t, l.Enabled(context.Background(), slog.LevelInfo))
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("context fields", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		l, buf := setupSlog(DebugLevel)
+		ctx := InjectFields(context.Background(), "request_id", "42")
+
+		// Act
+		l.InfoContext(ctx, "infoMsg", "with_field_1", "test")
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, "42", entry["request_id"])
+		assert.Equal(t, "test", entry["with_field_1"])
+	})
+
+	t.Run("attrs and groups", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		l, buf := setupSlog(DebugLevel)
+
+		// Act
+		l.With("component", "cache").
+			WithGroup("request").
+			With("method", "GET").
+			Info("infoMsg", slog.Group("peer", "addr", "127.0.0.1"), "status", 200)
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, "cache", entry["component"])
+		assert.Equal(t, map[string]any{
+			"method": "GET",
+			"status": float64(200),
+			"peer":   map[string]any{"addr": "127.0.0.1"},
+		}, entry["request"])
+	})
+
+	t.Run("inline group", func(t *testing.T) {
+		t.Parallel()
+
+		// Arrange
+		l, buf := setupSlog(DebugLevel)
+
+		// Act
+		l.Info("infoMsg", slog.Group("", "a", 1, "b", 2), slog.Group("empty"))
+
+		// Assert
+		entry := decodeEntry(t, buf.Bytes())
+		assert.Equal(t, float64(1), entry["a"])
+		assert.Equal(t, float64(2), entry["b"])
+		assert.NotContains(t, entry, "empty")
+	})
+}
+
+func TestSlogHandler_Conformance(t *testing.T) {
+	t.Parallel()
+
+	var buf *bytes.Buffer
+	newHandler := func(*testing.T) slog.Handler {
+		buf = &bytes.Buffer{}
+		return NewSlogHandler(NewLogger(DebugLevel, WithTimestamp(), WithOutput(buf)))
+	}
+	result := func(t *testing.T) map[string]any {
+		if strings.HasSuffix(t.Name(), "/zero-time") {
+			t.Skip("the time is set by the logger (see WithTimestamp), not taken from the record")
+		}
+
+		entry := decodeEntry(t, buf.Bytes())
+		entry[slog.MessageKey] = entry[zerolog.MessageFieldName]
+		delete(entry, zerolog.MessageFieldName)
+		return entry
+	}
+
+	slogtest.Run(t, newHandler, result)
+}
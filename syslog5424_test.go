@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: MIT
+
+package log_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/syslog"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/cdnnow-pro/go-log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rfc5424Pattern = regexp.MustCompile(`^<(\d+)>1 \S+ host app \d+ - (-|\[.*?[^\\]\]) ?(.*)$`)
+
+func TestStructuredSyslogWriter_UDP(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pc.Close() })
+
+	w, err := NewStructuredSyslogWriter("udp", pc.LocalAddr().String(),
+		WithSyslogHostname("host"), WithSyslogAppName("app"), WithSyslogFacility(syslog.LOG_LOCAL0))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Info(context.Background(), "started", "port", 8080, "path", `C:\ "x" ]`)
+	l.Error(context.Background(), errors.New("boom"), "failed")
+
+	// Assert
+	info := readDatagram(t, pc)
+	m := rfc5424Pattern.FindStringSubmatch(info)
+	require.NotNil(t, m, info)
+	assert.Equal(t, strconv.Itoa(int(syslog.LOG_LOCAL0|syslog.LOG_INFO)), m[1])
+	assert.Equal(t, `[fields@32473 port="8080" path="C:\\ \"x\" \]"]`, m[2])
+	assert.Equal(t, "started", m[3])
+
+	failed := readDatagram(t, pc)
+	m = rfc5424Pattern.FindStringSubmatch(failed)
+	require.NotNil(t, m, failed)
+	assert.Equal(t, strconv.Itoa(int(syslog.LOG_LOCAL0|syslog.LOG_ERR)), m[1])
+	assert.Equal(t, `[fields@32473 error="boom"]`, m[2])
+	assert.Equal(t, "failed", m[3])
+}
+
+func TestStructuredSyslogWriter_TCP(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	w, err := NewStructuredSyslogWriter("tcp", ln.Addr().String(),
+		WithSyslogHostname("host"), WithSyslogAppName("app"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	l := NewLogger(DebugLevel, WithOutput(w))
+
+	// Act
+	l.Warn(context.Background(), "first")
+
+	// Assert
+	first := <-conns
+	msg := readOctetCounted(t, bufio.NewReader(first))
+	m := rfc5424Pattern.FindStringSubmatch(msg)
+	require.NotNil(t, m, msg)
+	assert.Equal(t, strconv.Itoa(int(syslog.LOG_USER|syslog.LOG_WARNING)), m[1])
+	assert.Equal(t, "-", m[2])
+	assert.Equal(t, "first", m[3])
+
+	// The writer reconnects after the server closes the connection.
+	require.NoError(t, first.Close())
+	var second net.Conn
+	require.Eventually(t, func() bool {
+		l.Warn(context.Background(), "second")
+		select {
+		case second = <-conns:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, strings.HasSuffix(readOctetCounted(t, bufio.NewReader(second)), " second"))
+}
+
+func TestStructuredSyslogWriter_RetryDelay(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	w, err := NewStructuredSyslogWriter("tcp", addr, WithSyslogRetryDelay(300*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.NoError(t, ln.Close())
+	entry := []byte(`{"level":"INFO","message":"retried"}`)
+	require.Eventually(t, func() bool {
+		_, err := w.Write(entry)
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	// Act
+	_, err = w.Write(entry)
+
+	// Assert
+	require.Error(t, err, "the server is redialed before the retry delay")
+	assert.Eventually(t, func() bool {
+		_, err := w.Write(entry)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+// listenStalled starts a TCP server accepting the connections and never reading from them.
+func listenStalled(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestStructuredSyslogWriter_WriteTimeout(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	w, err := NewStructuredSyslogWriter("tcp", listenStalled(t), WithSyslogDialTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	// The message doesn't fit the socket buffers, so the write blocks until the deadline.
+	payload := strings.Repeat("x", 8<<20)
+
+	// Act
+	_, err = w.Write([]byte(`{"level":"INFO","message":"` + payload + `"}`))
+
+	// Assert
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
+
+func TestStructuredSyslogWriter_Unixgram(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	path := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pc.Close() })
+
+	w, err := NewStructuredSyslogWriter("unixgram", path, WithSyslogHostname("host"), WithSyslogAppName("app"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+
+	// Act
+	_, err = w.Write([]byte(`{"level":"DEBUG","message":"plain"}` + "\n"))
+
+	// Assert
+	require.NoError(t, err)
+	m := rfc5424Pattern.FindStringSubmatch(readDatagram(t, pc))
+	require.NotNil(t, m)
+	assert.Equal(t, strconv.Itoa(int(syslog.LOG_USER|syslog.LOG_DEBUG)), m[1])
+	assert.Equal(t, "plain", m[3])
+}
+
+func readDatagram(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+
+	buf := make([]byte, 64*1024)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	length, err := r.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	require.NoError(t, err)
+
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	require.NoError(t, err)
+	return string(msg)
+}